@@ -0,0 +1,135 @@
+// Package assign solves linear assignment problems.
+package assign
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	// ErrRagged is returned when the rows of a cost table differ in length.
+	ErrRagged = errors.New("assign: rows have differing lengths")
+	// ErrInvalidCost is returned when a cost is NaN or infinite, or so large
+	// in magnitude that sums of costs could overflow; see MaxCost.
+	ErrInvalidCost = errors.New("assign: cost is not finite or too large")
+)
+
+// MaxCost returns the largest cost magnitude Hungarian accepts for an n x m
+// table. The dual potentials are sums of up to n+m cost differences, so
+// costs are kept far enough below math.MaxFloat64 that they stay finite.
+func MaxCost(n, m int) float64 {
+	return math.MaxFloat64 / float64(4*(n+m))
+}
+
+// Hungarian finds a minimum-cost assignment of rows to columns in O(n²m)
+// time for an n x m cost table, using the shortest augmenting path
+// formulation of the Hungarian algorithm with potentials.
+//
+// Every row is assigned a distinct column when n ≤ m, and every column a
+// distinct row otherwise; match[i] is the column assigned to row i, or -1
+// if row i is left unassigned. total is the sum of the chosen costs.
+func Hungarian(cost [][]float64) (match []int, total float64, err error) {
+	n := len(cost)
+	if n == 0 {
+		return []int{}, 0, nil
+	}
+	m := len(cost[0])
+	limit := MaxCost(n, m)
+	for _, row := range cost {
+		if len(row) != m {
+			return nil, 0, ErrRagged
+		}
+		for _, c := range row {
+			// Written so that NaN is rejected too.
+			if !(math.Abs(c) <= limit) {
+				return nil, 0, ErrInvalidCost
+			}
+		}
+	}
+
+	match = make([]int, n)
+	for i := range match {
+		match[i] = -1
+	}
+	if n <= m {
+		for j, i := range solve(n, m, func(i, j int) float64 { return cost[i][j] }) {
+			if i >= 0 {
+				match[i] = j
+			}
+		}
+	} else {
+		// Solve the transposed problem so the smaller side is the one
+		// fully assigned.
+		copy(match, solve(m, n, func(i, j int) float64 { return cost[j][i] }))
+	}
+	for i, j := range match {
+		if j >= 0 {
+			total += cost[i][j]
+		}
+	}
+	return match, total, nil
+}
+
+// solve assigns each of n rows a distinct one of m ≥ n columns and returns,
+// for every column, its row or -1.
+func solve(n, m int, cost func(i, j int) float64) []int {
+	// 1-based arrays; column 0 is a virtual column holding the row being
+	// inserted.
+	u := make([]float64, n+1)
+	v := make([]float64, m+1)
+	p := make([]int, m+1) // p[j] is the row matched to column j, 0 if none
+	way := make([]int, m+1)
+	minv := make([]float64, m+1)
+	used := make([]bool, m+1)
+
+	for i := 1; i <= n; i++ {
+		p[0] = i
+		j0 := 0
+		for j := range minv {
+			minv[j] = math.Inf(1)
+			used[j] = false
+		}
+		for {
+			used[j0] = true
+			i0, delta, j1 := p[j0], math.Inf(1), 0
+			for j := 1; j <= m; j++ {
+				if used[j] {
+					continue
+				}
+				if cur := cost(i0-1, j-1) - u[i0] - v[j]; cur < minv[j] {
+					minv[j], way[j] = cur, j0
+				}
+				if minv[j] < delta {
+					delta, j1 = minv[j], j
+				}
+			}
+			for j := 0; j <= m; j++ {
+				if used[j] {
+					u[p[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+			if j1 == 0 {
+				// Only possible if the potentials stopped being finite.
+				panic("assign: no augmenting column")
+			}
+			j0 = j1
+			if p[j0] == 0 {
+				break
+			}
+		}
+		for j0 != 0 {
+			j1 := way[j0]
+			p[j0] = p[j1]
+			j0 = j1
+		}
+	}
+
+	rows := make([]int, m)
+	for j := 1; j <= m; j++ {
+		rows[j-1] = p[j] - 1
+	}
+	return rows
+}
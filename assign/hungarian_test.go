@@ -0,0 +1,118 @@
+package assign
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// bruteForce returns the minimum total over every injective assignment of
+// the smaller side into the larger.
+func bruteForce(cost [][]float64) float64 {
+	n, m := len(cost), len(cost[0])
+	best := math.Inf(1)
+	used := make([]bool, max(n, m))
+	var rec func(k int, sum float64)
+	rec = func(k int, sum float64) {
+		if k == min(n, m) {
+			best = min(best, sum)
+			return
+		}
+		for j := range max(n, m) {
+			if used[j] {
+				continue
+			}
+			used[j] = true
+			if n <= m {
+				rec(k+1, sum+cost[k][j])
+			} else {
+				rec(k+1, sum+cost[j][k])
+			}
+			used[j] = false
+		}
+	}
+	rec(0, 0)
+	return best
+}
+
+func TestHungarianAgainstBruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 500; trial++ {
+		n, m := 1+r.Intn(6), 1+r.Intn(6)
+		cost := make([][]float64, n)
+		for i := range cost {
+			cost[i] = make([]float64, m)
+			for j := range cost[i] {
+				cost[i][j] = float64(r.Intn(41) - 20)
+			}
+		}
+		match, total, err := Hungarian(cost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := bruteForce(cost); total != want {
+			t.Fatalf("%v: total = %v, want %v", cost, total, want)
+		}
+
+		sum, assigned := 0.0, 0
+		cols := map[int]bool{}
+		for i, j := range match {
+			if j < 0 {
+				continue
+			}
+			if cols[j] {
+				t.Fatalf("%v: column %d assigned twice in %v", cost, j, match)
+			}
+			cols[j] = true
+			sum += cost[i][j]
+			assigned++
+		}
+		if assigned != min(n, m) || sum != total {
+			t.Fatalf("%v: match %v assigns %d with sum %v; total %v", cost, match, assigned, sum, total)
+		}
+	}
+}
+
+func TestHungarianInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		cost [][]float64
+		want error
+	}{
+		{[][]float64{{1, 2}, {3}}, ErrRagged},
+		{[][]float64{{math.NaN()}}, ErrInvalidCost},
+		{[][]float64{{1, math.Inf(1)}}, ErrInvalidCost},
+		{[][]float64{{1e308, 0}, {0, -1e308}}, ErrInvalidCost},
+		{[][]float64{{-math.MaxFloat64}}, ErrInvalidCost},
+	} {
+		if _, _, err := Hungarian(tc.cost); !errors.Is(err, tc.want) {
+			t.Errorf("Hungarian(%v) error = %v, want %v", tc.cost, err, tc.want)
+		}
+	}
+	match, total, err := Hungarian(nil)
+	if err != nil || len(match) != 0 || total != 0 {
+		t.Errorf("Hungarian(nil) = %v, %v, %v", match, total, err)
+	}
+}
+
+func TestHungarianLargeCosts(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for trial := 0; trial < 200; trial++ {
+		n, m := 1+r.Intn(6), 1+r.Intn(6)
+		limit := MaxCost(n, m)
+		cost := make([][]float64, n)
+		for i := range cost {
+			cost[i] = make([]float64, m)
+			for j := range cost[i] {
+				cost[i][j] = float64(r.Intn(41)-20) / 20 * limit
+			}
+		}
+		_, total, err := Hungarian(cost)
+		if err != nil {
+			t.Fatalf("%dx%d costs within MaxCost: %v", n, m, err)
+		}
+		if want := bruteForce(cost); math.Abs(total-want) > 1e-12*limit {
+			t.Fatalf("%v: total = %v, want %v", cost, total, want)
+		}
+	}
+}
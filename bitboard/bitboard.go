@@ -0,0 +1,440 @@
+// Package bitboard provides bit-set representations of rectangular boards
+// for board-game engines.
+//
+// Bitboard packs an 8x8 board into a single uint64 with square a1 at bit 0
+// and h8 at bit 63, so set operations and one-step moves are single machine
+// instructions. Grid generalises the idea to boards of any size.
+package bitboard
+
+import (
+	"math/bits"
+	"strings"
+	"unsafe"
+)
+
+// Bitboard is a set of squares on an 8x8 board. Square indices run from 0
+// (a1) to 63 (h8), rank-major: square = rank*8 + file.
+type Bitboard uint64
+
+// Frequently used masks.
+const (
+	Empty Bitboard = 0
+	Full  Bitboard = ^Bitboard(0)
+
+	FileA Bitboard = 0x0101010101010101
+	FileH Bitboard = FileA << 7
+	Rank1 Bitboard = 0xff
+	Rank8 Bitboard = Rank1 << 56
+)
+
+// Square returns the index of the square on the given file and rank, both
+// in [0, 8).
+func Square(file, rank int) int {
+	return rank*8 + file
+}
+
+// File returns the bitboard of every square on file f.
+func File(f int) Bitboard {
+	return FileA << f
+}
+
+// Rank returns the bitboard of every square on rank r.
+func Rank(r int) Bitboard {
+	return Rank1 << (8 * r)
+}
+
+// Of returns the bitboard containing exactly the given squares.
+func Of(squares ...int) Bitboard {
+	var b Bitboard
+	for _, sq := range squares {
+		b |= 1 << sq
+	}
+	return b
+}
+
+// Has reports whether sq is in b.
+func (b Bitboard) Has(sq int) bool {
+	return b>>sq&1 == 1
+}
+
+// Set returns b with sq added.
+func (b Bitboard) Set(sq int) Bitboard {
+	return b | 1<<sq
+}
+
+// Clear returns b with sq removed.
+func (b Bitboard) Clear(sq int) Bitboard {
+	return b &^ (1 << sq)
+}
+
+// Toggle returns b with sq flipped.
+func (b Bitboard) Toggle(sq int) Bitboard {
+	return b ^ 1<<sq
+}
+
+// Count returns the number of squares in b.
+func (b Bitboard) Count() int {
+	return bits.OnesCount64(uint64(b))
+}
+
+// LSB returns the lowest square in b, or -1 if b is empty.
+func (b Bitboard) LSB() int {
+	if b == 0 {
+		return -1
+	}
+	return bits.TrailingZeros64(uint64(b))
+}
+
+// MSB returns the highest square in b, or -1 if b is empty.
+func (b Bitboard) MSB() int {
+	return 63 - bits.LeadingZeros64(uint64(b))
+}
+
+// PopLSB returns the lowest square in b and b without it. The square is -1
+// if b is empty.
+func (b Bitboard) PopLSB() (int, Bitboard) {
+	return b.LSB(), b & (b - 1)
+}
+
+// Squares calls fn for every square in b in ascending order until fn
+// returns false.
+func (b Bitboard) Squares(fn func(sq int) bool) {
+	for b != 0 {
+		sq := bits.TrailingZeros64(uint64(b))
+		if !fn(sq) {
+			return
+		}
+		b &= b - 1
+	}
+}
+
+// North shifts every square one rank up; squares on rank 8 fall off.
+func (b Bitboard) North() Bitboard { return b << 8 }
+
+// South shifts every square one rank down; squares on rank 1 fall off.
+func (b Bitboard) South() Bitboard { return b >> 8 }
+
+// East shifts every square one file towards h without wrapping.
+func (b Bitboard) East() Bitboard { return (b &^ FileH) << 1 }
+
+// West shifts every square one file towards a without wrapping.
+func (b Bitboard) West() Bitboard { return (b &^ FileA) >> 1 }
+
+// NorthEast shifts every square one step diagonally up and towards h.
+func (b Bitboard) NorthEast() Bitboard { return (b &^ FileH) << 9 }
+
+// NorthWest shifts every square one step diagonally up and towards a.
+func (b Bitboard) NorthWest() Bitboard { return (b &^ FileA) << 7 }
+
+// SouthEast shifts every square one step diagonally down and towards h.
+func (b Bitboard) SouthEast() Bitboard { return (b &^ FileH) >> 7 }
+
+// SouthWest shifts every square one step diagonally down and towards a.
+func (b Bitboard) SouthWest() Bitboard { return (b &^ FileA) >> 9 }
+
+// Shift moves every square df files and dr ranks, dropping squares that
+// leave the board.
+func (b Bitboard) Shift(df, dr int) Bitboard {
+	for ; df > 0; df-- {
+		b = b.East()
+	}
+	for ; df < 0; df++ {
+		b = b.West()
+	}
+	switch {
+	case dr >= 8 || dr <= -8:
+		return 0
+	case dr > 0:
+		return b << (8 * dr)
+	case dr < 0:
+		return b >> (-8 * dr)
+	}
+	return b
+}
+
+// KingAttacks returns the squares a king on any square of b attacks.
+func (b Bitboard) KingAttacks() Bitboard {
+	row := b | b.East() | b.West()
+	return (row | row.North() | row.South()) &^ b
+}
+
+// KnightAttacks returns the squares a knight on any square of b attacks.
+func (b Bitboard) KnightAttacks() Bitboard {
+	return b.Shift(1, 2) | b.Shift(2, 1) | b.Shift(2, -1) | b.Shift(1, -2) |
+		b.Shift(-1, -2) | b.Shift(-2, -1) | b.Shift(-2, 1) | b.Shift(-1, 2)
+}
+
+// Slide returns the squares reached by moving repeatedly in direction step
+// from every square of b, stopping at (and including) the first square in
+// occupied.
+func (b Bitboard) Slide(step func(Bitboard) Bitboard, occupied Bitboard) Bitboard {
+	var reach Bitboard
+	for frontier := step(b); frontier != 0; frontier = step(frontier &^ occupied) {
+		reach |= frontier
+	}
+	return reach
+}
+
+// RookAttacks returns the squares attacked by rooks on b given the
+// occupied squares.
+func (b Bitboard) RookAttacks(occupied Bitboard) Bitboard {
+	return b.Slide(Bitboard.North, occupied) | b.Slide(Bitboard.South, occupied) |
+		b.Slide(Bitboard.East, occupied) | b.Slide(Bitboard.West, occupied)
+}
+
+// BishopAttacks returns the squares attacked by bishops on b given the
+// occupied squares.
+func (b Bitboard) BishopAttacks(occupied Bitboard) Bitboard {
+	return b.Slide(Bitboard.NorthEast, occupied) | b.Slide(Bitboard.NorthWest, occupied) |
+		b.Slide(Bitboard.SouthEast, occupied) | b.Slide(Bitboard.SouthWest, occupied)
+}
+
+// String renders b as eight lines, rank 8 first, using 'x' for set squares
+// and '.' for empty ones.
+func (b Bitboard) String() string {
+	var sb strings.Builder
+	for rank := 7; rank >= 0; rank-- {
+		for file := 0; file < 8; file++ {
+			if b.Has(Square(file, rank)) {
+				sb.WriteByte('x')
+			} else {
+				sb.WriteByte('.')
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// Grid is a set of cells on a board of arbitrary size. Cell (row, col) has
+// index row*Cols + col.
+type Grid struct {
+	rows, cols int
+	words      []uint64
+}
+
+// NewGrid returns an empty rows x cols grid.
+func NewGrid(rows, cols int) *Grid {
+	return &Grid{rows: rows, cols: cols, words: make([]uint64, (rows*cols+63)/64)}
+}
+
+// Rows returns the number of rows.
+func (g *Grid) Rows() int {
+	return g.rows
+}
+
+// Cols returns the number of columns.
+func (g *Grid) Cols() int {
+	return g.cols
+}
+
+// Sizeof returns the approximate heap usage of the grid in bytes, one bit
+// per cell rounded up to whole words.
+func (g *Grid) Sizeof() int {
+	return int(unsafe.Sizeof(*g)) + 8*cap(g.words)
+}
+
+func (g *Grid) index(row, col int) int {
+	if row < 0 || row >= g.rows || col < 0 || col >= g.cols {
+		panic("bitboard: cell out of range")
+	}
+	return row*g.cols + col
+}
+
+// Has reports whether cell (row, col) is set.
+func (g *Grid) Has(row, col int) bool {
+	i := g.index(row, col)
+	return g.words[i/64]>>(i%64)&1 == 1
+}
+
+// Set adds cell (row, col).
+func (g *Grid) Set(row, col int) {
+	i := g.index(row, col)
+	g.words[i/64] |= 1 << (i % 64)
+}
+
+// Clear removes cell (row, col).
+func (g *Grid) Clear(row, col int) {
+	i := g.index(row, col)
+	g.words[i/64] &^= 1 << (i % 64)
+}
+
+// Count returns the number of set cells.
+func (g *Grid) Count() int {
+	n := 0
+	for _, w := range g.words {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// Cells calls fn for every set cell in index order until fn returns false.
+func (g *Grid) Cells(fn func(row, col int) bool) {
+	for wi, w := range g.words {
+		for w != 0 {
+			i := wi*64 + bits.TrailingZeros64(w)
+			if !fn(i/g.cols, i%g.cols) {
+				return
+			}
+			w &= w - 1
+		}
+	}
+}
+
+// Clone returns an independent copy of g.
+func (g *Grid) Clone() *Grid {
+	return &Grid{rows: g.rows, cols: g.cols, words: append([]uint64(nil), g.words...)}
+}
+
+// Shift returns a new grid with every cell moved dr rows and dc columns;
+// cells that leave the board are dropped. Like Bitboard.Shift it works a
+// word at a time: the whole board shifts by dr*Cols+dc bits, and the
+// columns that wrapped around a row edge are masked off.
+func (g *Grid) Shift(dr, dc int) *Grid {
+	out := NewGrid(g.rows, g.cols)
+	if dr <= -g.rows || dr >= g.rows || dc <= -g.cols || dc >= g.cols {
+		return out
+	}
+	shiftWords(out.words, g.words, dr*g.cols+dc)
+	for row := 0; row < g.rows; row++ {
+		if dc > 0 {
+			clearBits(out.words, row*g.cols, row*g.cols+dc)
+		} else if dc < 0 {
+			clearBits(out.words, (row+1)*g.cols+dc, (row+1)*g.cols)
+		}
+	}
+	out.clearTail()
+	return out
+}
+
+// shiftWords stores in dst the bit vector src moved k bits towards higher
+// indices, or -k bits towards lower ones if k is negative.
+func shiftWords(dst, src []uint64, k int) {
+	if k >= 0 {
+		ws, bs := k/64, uint(k%64)
+		for i := len(dst) - 1; i >= ws; i-- {
+			dst[i] = src[i-ws] << bs
+			if bs != 0 && i-ws > 0 {
+				dst[i] |= src[i-ws-1] >> (64 - bs)
+			}
+		}
+		return
+	}
+	ws, bs := -k/64, uint(-k%64)
+	for i := 0; i+ws < len(src); i++ {
+		dst[i] = src[i+ws] >> bs
+		if bs != 0 && i+ws+1 < len(src) {
+			dst[i] |= src[i+ws+1] << (64 - bs)
+		}
+	}
+}
+
+// clearBits clears bits [lo, hi) of the bit vector words.
+func clearBits(words []uint64, lo, hi int) {
+	for lo < hi {
+		b := uint(lo % 64)
+		n := min(hi-lo, 64-int(b))
+		words[lo/64] &^= ^uint64(0) >> (64 - n) << b
+		lo += n
+	}
+}
+
+// clearTail clears the bits past the last cell, which the word-level
+// operations may have set.
+func (g *Grid) clearTail() {
+	if tail := g.rows * g.cols % 64; tail != 0 {
+		g.words[len(g.words)-1] &= 1<<tail - 1
+	}
+}
+
+// And returns the intersection of g and other, which must have the same
+// shape.
+func (g *Grid) And(other *Grid) *Grid {
+	return g.combine(other, func(a, b uint64) uint64 { return a & b })
+}
+
+// Or returns the union of g and other, which must have the same shape.
+func (g *Grid) Or(other *Grid) *Grid {
+	return g.combine(other, func(a, b uint64) uint64 { return a | b })
+}
+
+// Xor returns the symmetric difference of g and other, which must have the
+// same shape.
+func (g *Grid) Xor(other *Grid) *Grid {
+	return g.combine(other, func(a, b uint64) uint64 { return a ^ b })
+}
+
+// AndNot returns the cells of g not in other, which must have the same
+// shape.
+func (g *Grid) AndNot(other *Grid) *Grid {
+	return g.combine(other, func(a, b uint64) uint64 { return a &^ b })
+}
+
+// Not returns the complement of g.
+func (g *Grid) Not() *Grid {
+	out := g.Clone()
+	for i := range out.words {
+		out.words[i] = ^out.words[i]
+	}
+	out.clearTail()
+	return out
+}
+
+func (g *Grid) combine(other *Grid, op func(a, b uint64) uint64) *Grid {
+	if g.rows != other.rows || g.cols != other.cols {
+		panic("bitboard: grid shapes differ")
+	}
+	out := NewGrid(g.rows, g.cols)
+	for i := range out.words {
+		out.words[i] = op(g.words[i], other.words[i])
+	}
+	return out
+}
+
+// FromBools builds a grid from a row-major boolean table. All rows must
+// have the same length.
+func FromBools(cells [][]bool) *Grid {
+	cols := 0
+	if len(cells) > 0 {
+		cols = len(cells[0])
+	}
+	g := NewGrid(len(cells), cols)
+	for r, row := range cells {
+		if len(row) != cols {
+			panic("bitboard: ragged rows")
+		}
+		for c, set := range row {
+			if set {
+				g.Set(r, c)
+			}
+		}
+	}
+	return g
+}
+
+// Bools returns g as a row-major boolean table.
+func (g *Grid) Bools() [][]bool {
+	out := make([][]bool, g.rows)
+	for r := range out {
+		out[r] = make([]bool, g.cols)
+	}
+	g.Cells(func(row, col int) bool {
+		out[row][col] = true
+		return true
+	})
+	return out
+}
+
+// Grid converts b to an 8x8 grid with row = rank and col = file.
+func (b Bitboard) Grid() *Grid {
+	return &Grid{rows: 8, cols: 8, words: []uint64{uint64(b)}}
+}
+
+// Bitboard converts an 8x8 grid back to a Bitboard. It panics if g is not
+// 8x8.
+func (g *Grid) Bitboard() Bitboard {
+	if g.rows != 8 || g.cols != 8 {
+		panic("bitboard: grid is not 8x8")
+	}
+	return Bitboard(g.words[0])
+}
@@ -0,0 +1,255 @@
+package bitboard
+
+import (
+	"math/rand"
+	"testing"
+	"unsafe"
+)
+
+// squaresOf converts b to a set of (file, rank) coordinates.
+func squaresOf(b Bitboard) map[[2]int]bool {
+	m := make(map[[2]int]bool)
+	for sq := 0; sq < 64; sq++ {
+		if b.Has(sq) {
+			m[[2]int{sq % 8, sq / 8}] = true
+		}
+	}
+	return m
+}
+
+func onBoard(f, r int) bool {
+	return f >= 0 && f < 8 && r >= 0 && r < 8
+}
+
+// attacks moves every square of b by each delta, repeating while slide is
+// set and the previous square was empty.
+func attacks(b, occupied Bitboard, deltas [][2]int, slide bool) Bitboard {
+	var out Bitboard
+	for p := range squaresOf(b) {
+		for _, d := range deltas {
+			f, r := p[0]+d[0], p[1]+d[1]
+			for onBoard(f, r) {
+				out = out.Set(Square(f, r))
+				if !slide || occupied.Has(Square(f, r)) {
+					break
+				}
+				f, r = f+d[0], r+d[1]
+			}
+		}
+	}
+	return out
+}
+
+var (
+	kingDeltas   = [][2]int{{-1, -1}, {-1, 0}, {-1, 1}, {0, -1}, {0, 1}, {1, -1}, {1, 0}, {1, 1}}
+	knightDeltas = [][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+	rookDeltas   = [][2]int{{0, 1}, {0, -1}, {1, 0}, {-1, 0}}
+	bishopDeltas = [][2]int{{1, 1}, {-1, 1}, {1, -1}, {-1, -1}}
+)
+
+func TestAttacksAgainstCoordinates(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		occupied := Bitboard(r.Uint64() & r.Uint64())
+		piece := Of(r.Intn(64))
+		if got, want := piece.KingAttacks(), attacks(piece, 0, kingDeltas, false); got != want {
+			t.Fatalf("KingAttacks(%d):\n%vwant\n%v", piece.LSB(), got, want)
+		}
+		if got, want := piece.KnightAttacks(), attacks(piece, 0, knightDeltas, false); got != want {
+			t.Fatalf("KnightAttacks(%d):\n%vwant\n%v", piece.LSB(), got, want)
+		}
+		if got, want := piece.RookAttacks(occupied), attacks(piece, occupied, rookDeltas, true); got != want {
+			t.Fatalf("RookAttacks(%d):\n%vwant\n%v", piece.LSB(), got, want)
+		}
+		if got, want := piece.BishopAttacks(occupied), attacks(piece, occupied, bishopDeltas, true); got != want {
+			t.Fatalf("BishopAttacks(%d):\n%vwant\n%v", piece.LSB(), got, want)
+		}
+	}
+}
+
+func TestShiftAgainstCoordinates(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 500; i++ {
+		b := Bitboard(r.Uint64())
+		df, dr := r.Intn(19)-9, r.Intn(19)-9
+		var want Bitboard
+		for p := range squaresOf(b) {
+			if f, rk := p[0]+df, p[1]+dr; onBoard(f, rk) {
+				want = want.Set(Square(f, rk))
+			}
+		}
+		if got := b.Shift(df, dr); got != want {
+			t.Fatalf("Shift(%d, %d) of\n%vgot\n%vwant\n%v", df, dr, b, got, want)
+		}
+	}
+}
+
+func TestSquareOps(t *testing.T) {
+	b := Of(0, 9, 63)
+	for _, tc := range []struct {
+		name      string
+		got, want int
+	}{
+		{"Count", b.Count(), 3},
+		{"LSB", b.LSB(), 0},
+		{"MSB", b.MSB(), 63},
+		{"Empty.LSB", Empty.LSB(), -1},
+		{"Empty.MSB", Empty.MSB(), -1},
+		{"File(3).Count", File(3).Count(), 8},
+		{"Rank(5).Count", Rank(5).Count(), 8},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s = %d, want %d", tc.name, tc.got, tc.want)
+		}
+	}
+	sq, rest := b.PopLSB()
+	if sq != 0 || rest != Of(9, 63) {
+		t.Errorf("PopLSB() = %d, %#x", sq, uint64(rest))
+	}
+	if b.Clear(9).Toggle(9).Set(1) != Of(0, 1, 9, 63) {
+		t.Errorf("Clear/Toggle/Set mismatch")
+	}
+	var seen []int
+	b.Squares(func(sq int) bool {
+		seen = append(seen, sq)
+		return len(seen) < 2
+	})
+	if len(seen) != 2 || seen[0] != 0 || seen[1] != 9 {
+		t.Errorf("Squares stopped at %v", seen)
+	}
+	if b.Grid().Bitboard() != b {
+		t.Errorf("Grid round trip changed %#x", uint64(b))
+	}
+}
+
+func TestGridAgainstBools(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for _, shape := range [][2]int{{1, 1}, {3, 5}, {8, 8}, {7, 13}, {10, 10}} {
+		rows, cols := shape[0], shape[1]
+		random := func() [][]bool {
+			m := make([][]bool, rows)
+			for i := range m {
+				m[i] = make([]bool, cols)
+				for j := range m[i] {
+					m[i][j] = r.Intn(2) == 0
+				}
+			}
+			return m
+		}
+		a, b := random(), random()
+		ga, gb := FromBools(a), FromBools(b)
+		dr, dc := r.Intn(5)-2, r.Intn(5)-2
+		shifted := ga.Shift(dr, dc)
+		and, or, xor, andNot, not := ga.And(gb), ga.Or(gb), ga.Xor(gb), ga.AndNot(gb), ga.Not()
+		count := 0
+		for i := 0; i < rows; i++ {
+			for j := 0; j < cols; j++ {
+				x, y := a[i][j], b[i][j]
+				if x {
+					count++
+				}
+				for _, tc := range []struct {
+					name string
+					g    *Grid
+					want bool
+				}{
+					{"And", and, x && y},
+					{"Or", or, x || y},
+					{"Xor", xor, x != y},
+					{"AndNot", andNot, x && !y},
+					{"Not", not, !x},
+				} {
+					if tc.g.Has(i, j) != tc.want {
+						t.Fatalf("%dx%d %s at (%d, %d) = %v", rows, cols, tc.name, i, j, !tc.want)
+					}
+				}
+				si, sj := i-dr, j-dc
+				want := si >= 0 && si < rows && sj >= 0 && sj < cols && a[si][sj]
+				if shifted.Has(i, j) != want {
+					t.Fatalf("%dx%d Shift(%d, %d) at (%d, %d) = %v", rows, cols, dr, dc, i, j, !want)
+				}
+			}
+		}
+		if ga.Count() != count {
+			t.Fatalf("%dx%d Count() = %d, want %d", rows, cols, ga.Count(), count)
+		}
+		if not.Count() != rows*cols-count {
+			t.Fatalf("%dx%d Not().Count() = %d, want %d", rows, cols, not.Count(), rows*cols-count)
+		}
+		back := ga.Bools()
+		for i := range a {
+			for j := range a[i] {
+				if back[i][j] != a[i][j] {
+					t.Fatalf("%dx%d Bools() differs at (%d, %d)", rows, cols, i, j)
+				}
+			}
+		}
+	}
+}
+
+func TestGridShift(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	for _, shape := range [][2]int{{0, 5}, {1, 1}, {1, 200}, {200, 1}, {3, 5}, {8, 8}, {9, 70}, {20, 33}} {
+		rows, cols := shape[0], shape[1]
+		g := NewGrid(rows, cols)
+		for i := 0; i < rows; i++ {
+			for j := 0; j < cols; j++ {
+				if r.Intn(2) == 0 {
+					g.Set(i, j)
+				}
+			}
+		}
+		for iter := 0; iter < 200; iter++ {
+			dr, dc := r.Intn(2*rows+3)-rows-1, r.Intn(2*cols+3)-cols-1
+			shifted := g.Shift(dr, dc)
+			count := 0
+			for i := 0; i < rows; i++ {
+				for j := 0; j < cols; j++ {
+					si, sj := i-dr, j-dc
+					want := si >= 0 && si < rows && sj >= 0 && sj < cols && g.Has(si, sj)
+					if want {
+						count++
+					}
+					if shifted.Has(i, j) != want {
+						t.Fatalf("%dx%d Shift(%d, %d) at (%d, %d) = %v", rows, cols, dr, dc, i, j, !want)
+					}
+				}
+			}
+			// Bits past the last cell must stay clear for Count and Not.
+			if shifted.Count() != count {
+				t.Fatalf("%dx%d Shift(%d, %d).Count() = %d, want %d", rows, cols, dr, dc, shifted.Count(), count)
+			}
+		}
+	}
+}
+
+func TestGridPanics(t *testing.T) {
+	for name, fn := range map[string]func(){
+		"out of range": func() { NewGrid(2, 2).Set(2, 0) },
+		"shape":        func() { NewGrid(2, 2).And(NewGrid(2, 3)) },
+		"ragged":       func() { FromBools([][]bool{{true}, {true, false}}) },
+		"not 8x8":      func() { NewGrid(4, 4).Bitboard() },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: no panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
+
+func TestGridSizeof(t *testing.T) {
+	header := int(unsafe.Sizeof(Grid{}))
+	for _, tc := range []struct{ rows, cols, words int }{
+		{0, 0, 0}, {1, 1, 1}, {8, 8, 1}, {8, 9, 2}, {19, 19, 6}, {100, 64, 100},
+	} {
+		g := NewGrid(tc.rows, tc.cols)
+		want := header + 8*tc.words
+		if got := g.Sizeof(); got != want {
+			t.Fatalf("%dx%d: Sizeof() = %d, want %d", tc.rows, tc.cols, got, want)
+		}
+	}
+}
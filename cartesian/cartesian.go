@@ -0,0 +1,205 @@
+// Package cartesian builds Cartesian trees over static arrays and uses them
+// to answer range-minimum queries in constant time.
+//
+// A (min-)Cartesian tree is a binary tree whose in-order traversal yields
+// the original array and whose nodes satisfy the heap property. The minimum
+// of any range [lo, hi) is the lowest common ancestor of positions lo and
+// hi-1, so an O(1) LCA structure over the tree doubles as an O(1) RMQ.
+package cartesian
+
+import (
+	"cmp"
+	"math/bits"
+	"unsafe"
+)
+
+// None marks a missing parent or child.
+const None = -1
+
+const intSize = int(unsafe.Sizeof(0))
+
+// Tree is a Cartesian tree stored as index arrays: node i is the array
+// element at position i.
+type Tree struct {
+	Root   int
+	Parent []int
+	Left   []int
+	Right  []int
+}
+
+// Build constructs the min-Cartesian tree of values in O(n) using the
+// classic right-spine stack. When several elements share the minimum the
+// leftmost one becomes the ancestor.
+func Build[T cmp.Ordered](values []T) *Tree {
+	n := len(values)
+	t := &Tree{
+		Root:   None,
+		Parent: make([]int, n),
+		Left:   make([]int, n),
+		Right:  make([]int, n),
+	}
+	stack := make([]int, 0, n)
+	for i, v := range values {
+		t.Parent[i], t.Left[i], t.Right[i] = None, None, None
+		last := None
+		for len(stack) > 0 && values[stack[len(stack)-1]] > v {
+			last = stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+		}
+		if last != None {
+			t.Left[i] = last
+			t.Parent[last] = i
+		}
+		if len(stack) > 0 {
+			top := stack[len(stack)-1]
+			t.Right[top] = i
+			t.Parent[i] = top
+		}
+		stack = append(stack, i)
+	}
+	if len(stack) > 0 {
+		t.Root = stack[0]
+	}
+	return t
+}
+
+// Len returns the number of nodes in the tree.
+func (t *Tree) Len() int {
+	return len(t.Parent)
+}
+
+// Sizeof returns the approximate heap usage of the tree in bytes.
+func (t *Tree) Sizeof() int {
+	return int(unsafe.Sizeof(*t)) + intSize*(cap(t.Parent)+cap(t.Left)+cap(t.Right))
+}
+
+// RMQ answers range-minimum queries over an immutable slice. Construction
+// takes O(n log n) time and space; each query takes O(1).
+type RMQ[T cmp.Ordered] struct {
+	values []T
+	tree   *Tree
+	first  []int   // first occurrence of each node in the Euler tour
+	euler  []int   // Euler tour of the Cartesian tree
+	depth  []int   // depth of each node
+	sparse [][]int // sparse[k][i]: shallowest tour entry in euler[i:i+2^k]
+}
+
+// NewRMQ preprocesses values for range-minimum queries. The slice is
+// retained and must not be modified afterwards.
+func NewRMQ[T cmp.Ordered](values []T) *RMQ[T] {
+	r := &RMQ[T]{values: values, tree: Build(values)}
+	n := len(values)
+	if n == 0 {
+		return r
+	}
+
+	r.first = make([]int, n)
+	r.depth = make([]int, n)
+	r.euler = make([]int, 0, 2*n-1)
+	r.tour()
+
+	m := len(r.euler)
+	levels := bits.Len(uint(m))
+	r.sparse = make([][]int, levels)
+	r.sparse[0] = r.euler
+	for k := 1; k < levels; k++ {
+		prev := r.sparse[k-1]
+		half := 1 << (k - 1)
+		row := make([]int, m-(1<<k)+1)
+		for i := range row {
+			row[i] = r.shallower(prev[i], prev[i+half])
+		}
+		r.sparse[k] = row
+	}
+	return r
+}
+
+// Tree returns the underlying Cartesian tree.
+func (r *RMQ[T]) Tree() *Tree {
+	return r.tree
+}
+
+// Len returns the length of the indexed slice.
+func (r *RMQ[T]) Len() int {
+	return len(r.values)
+}
+
+// Sizeof returns the approximate heap usage of the index in bytes,
+// including its Cartesian tree but not the indexed slice, which belongs to
+// the caller.
+func (r *RMQ[T]) Sizeof() int {
+	size := int(unsafe.Sizeof(*r)) + r.tree.Sizeof() +
+		intSize*(cap(r.first)+cap(r.depth)) + cap(r.sparse)*int(unsafe.Sizeof(r.euler))
+	// sparse[0] is euler itself.
+	for _, row := range r.sparse {
+		size += intSize * cap(row)
+	}
+	return size
+}
+
+// Index returns the position of the minimum in values[lo:hi]. Ties resolve
+// to the leftmost position. It panics if the range is empty or out of
+// bounds.
+func (r *RMQ[T]) Index(lo, hi int) int {
+	if lo < 0 || hi > len(r.values) || lo >= hi {
+		panic("cartesian: invalid range")
+	}
+	return r.LCA(lo, hi-1)
+}
+
+// Min returns the minimum of values[lo:hi].
+func (r *RMQ[T]) Min(lo, hi int) T {
+	return r.values[r.Index(lo, hi)]
+}
+
+// LCA returns the lowest common ancestor of nodes u and v in the Cartesian
+// tree.
+func (r *RMQ[T]) LCA(u, v int) int {
+	a, b := r.first[u], r.first[v]
+	if a > b {
+		a, b = b, a
+	}
+	k := bits.Len(uint(b-a+1)) - 1
+	return r.shallower(r.sparse[k][a], r.sparse[k][b-(1<<k)+1])
+}
+
+func (r *RMQ[T]) shallower(u, v int) int {
+	if r.depth[v] < r.depth[u] {
+		return v
+	}
+	return u
+}
+
+// tour records the Euler tour iteratively so that degenerate (sorted)
+// inputs don't exhaust the goroutine stack.
+func (r *RMQ[T]) tour() {
+	t := r.tree
+	type frame struct{ node, state int }
+	stack := []frame{{t.Root, 0}}
+	r.first[t.Root] = 0
+	r.euler = append(r.euler, t.Root)
+	for len(stack) > 0 {
+		f := &stack[len(stack)-1]
+		var child int
+		switch f.state {
+		case 0:
+			child = t.Left[f.node]
+		case 1:
+			child = t.Right[f.node]
+		default:
+			stack = stack[:len(stack)-1]
+			if len(stack) > 0 {
+				r.euler = append(r.euler, stack[len(stack)-1].node)
+			}
+			continue
+		}
+		f.state++
+		if child == None {
+			continue
+		}
+		r.depth[child] = r.depth[f.node] + 1
+		r.first[child] = len(r.euler)
+		r.euler = append(r.euler, child)
+		stack = append(stack, frame{child, 0})
+	}
+}
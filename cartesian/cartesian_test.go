@@ -0,0 +1,109 @@
+package cartesian
+
+import (
+	"math/rand"
+	"testing"
+	"unsafe"
+)
+
+func TestBuildInvariants(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	values := make([]int, 200)
+	for i := range values {
+		values[i] = r.Intn(20)
+	}
+	tr := Build(values)
+	var order []int
+	var walk func(v int)
+	walk = func(v int) {
+		if v == None {
+			return
+		}
+		walk(tr.Left[v])
+		order = append(order, v)
+		walk(tr.Right[v])
+	}
+	walk(tr.Root)
+	for i, v := range order {
+		if i != v {
+			t.Fatalf("in-order position %d holds node %d", i, v)
+		}
+	}
+	for v, p := range tr.Parent {
+		if p != None && values[p] > values[v] {
+			t.Fatalf("heap property violated at %d", v)
+		}
+	}
+}
+
+func TestRMQAgainstScan(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for _, n := range []int{1, 2, 3, 17, 100} {
+		values := make([]int, n)
+		for i := range values {
+			values[i] = r.Intn(10)
+		}
+		q := NewRMQ(values)
+		for lo := 0; lo < n; lo++ {
+			best := lo
+			for hi := lo + 1; hi <= n; hi++ {
+				if values[hi-1] < values[best] {
+					best = hi - 1
+				}
+				if got := q.Index(lo, hi); got != best {
+					t.Fatalf("n=%d Index(%d, %d) = %d, want %d", n, lo, hi, got, best)
+				}
+			}
+		}
+	}
+}
+
+func TestSortedInput(t *testing.T) {
+	values := make([]int, 1<<17)
+	for i := range values {
+		values[i] = i
+	}
+	q := NewRMQ(values)
+	if got := q.Index(1000, len(values)); got != 1000 {
+		t.Fatalf("Index = %d, want 1000", got)
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	q := NewRMQ([]int(nil))
+	if q.Len() != 0 || q.Tree().Root != None {
+		t.Fatal("empty RMQ should have no root")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Index on empty range did not panic")
+		}
+	}()
+	q.Index(0, 0)
+}
+
+func TestSizeof(t *testing.T) {
+	r := rand.New(rand.NewSource(9))
+	row := int(unsafe.Sizeof([]int(nil)))
+	for _, n := range []int{1, 2, 17, 1000} {
+		values := make([]int, n)
+		for i := range values {
+			values[i] = r.Intn(50)
+		}
+		rmq := NewRMQ(values)
+		tree := int(unsafe.Sizeof(Tree{})) + 3*n*intSize
+		if got := rmq.Tree().Sizeof(); got != tree {
+			t.Fatalf("n=%d: Tree.Sizeof() = %d, want %d", n, got, tree)
+		}
+		// The Euler tour has m = 2n-1 entries, and sparse row k keeps the
+		// minima of its m-2^k+1 windows of length 2^k, row 0 being the tour.
+		m := 2*n - 1
+		want := int(unsafe.Sizeof(*rmq)) + tree + 2*n*intSize
+		for k := 0; 1<<k <= m; k++ {
+			want += row + (m-1<<k+1)*intSize
+		}
+		if got := rmq.Sizeof(); got != want {
+			t.Fatalf("n=%d: RMQ.Sizeof() = %d, want %d", n, got, want)
+		}
+	}
+}
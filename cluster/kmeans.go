@@ -0,0 +1,263 @@
+// Package cluster implements clustering algorithms over points stored as
+// rows of a [][]float64 table.
+package cluster
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"slices"
+)
+
+var (
+	// ErrNoData is returned when there are no points to cluster.
+	ErrNoData = errors.New("cluster: no data")
+	// ErrRagged is returned when points have differing dimensions.
+	ErrRagged = errors.New("cluster: points have differing dimensions")
+	// ErrInvalidK is returned when k is not in [1, number of points].
+	ErrInvalidK = errors.New("cluster: k out of range")
+)
+
+// Distance measures the dissimilarity of two points of equal dimension.
+type Distance func(a, b []float64) float64
+
+// SquaredEuclidean is the squared Euclidean distance, the metric k-means
+// minimises.
+func SquaredEuclidean(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return sum
+}
+
+// Euclidean is the Euclidean (L2) distance.
+func Euclidean(a, b []float64) float64 {
+	return math.Sqrt(SquaredEuclidean(a, b))
+}
+
+// Manhattan is the L1 distance.
+func Manhattan(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		sum += math.Abs(a[i] - b[i])
+	}
+	return sum
+}
+
+// Cosine is one minus the cosine similarity. It is 1 when either point is
+// the zero vector.
+func Cosine(a, b []float64) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 1
+	}
+	return 1 - dot/math.Sqrt(na*nb)
+}
+
+// KMeansOptions configures KMeans. The zero value is usable.
+type KMeansOptions struct {
+	// MaxIter bounds the number of Lloyd iterations. Defaults to 300.
+	MaxIter int
+	// Tol stops iterating once no centroid moves further than Tol under
+	// Distance. Defaults to 1e-9.
+	Tol float64
+	// Distance assigns points to centroids and weights k-means++ seeding.
+	// Defaults to SquaredEuclidean. Centroids are always updated to the mean
+	// of their points, so metrics other than (squared) Euclidean give
+	// Lloyd-style heuristics rather than true minimisers.
+	Distance Distance
+	// Rand drives k-means++ seeding. Defaults to a generator with a fixed
+	// seed so results are reproducible.
+	Rand *rand.Rand
+}
+
+// KMeansResult is the outcome of KMeans.
+type KMeansResult struct {
+	// Labels[i] is the cluster of data[i].
+	Labels []int
+	// Centroids[c] is the centre of cluster c: the mean of its points as of
+	// the last Lloyd iteration. Labels are assigned once more afterwards,
+	// so when Converged is false a few points may have moved since.
+	Centroids [][]float64
+	// Inertia is the sum of Distance from every point to its centroid.
+	Inertia float64
+	// Iterations is the number of Lloyd iterations performed.
+	Iterations int
+	// Converged reports whether Tol was reached before MaxIter.
+	Converged bool
+}
+
+// KMeans partitions the rows of data into k clusters using k-means++
+// seeding followed by Lloyd's algorithm.
+func KMeans(data [][]float64, k int, opts KMeansOptions) (*KMeansResult, error) {
+	if len(data) == 0 {
+		return nil, ErrNoData
+	}
+	dim := len(data[0])
+	for _, p := range data {
+		if len(p) != dim {
+			return nil, ErrRagged
+		}
+	}
+	if k < 1 || k > len(data) {
+		return nil, ErrInvalidK
+	}
+	if opts.MaxIter <= 0 {
+		opts.MaxIter = 300
+	}
+	if opts.Tol <= 0 {
+		opts.Tol = 1e-9
+	}
+	if opts.Distance == nil {
+		opts.Distance = SquaredEuclidean
+	}
+	if opts.Rand == nil {
+		opts.Rand = rand.New(rand.NewSource(1))
+	}
+
+	res := &KMeansResult{
+		Labels:    make([]int, len(data)),
+		Centroids: seed(data, k, opts.Distance, opts.Rand),
+	}
+	dist := make([]float64, len(data))
+	counts := make([]int, k)
+	next := make([][]float64, k)
+	for c := range next {
+		next[c] = make([]float64, dim)
+	}
+
+	for res.Iterations < opts.MaxIter {
+		res.Iterations++
+		assign(data, res.Centroids, opts.Distance, res.Labels, dist)
+
+		for c := range next {
+			clear(next[c])
+			counts[c] = 0
+		}
+		for i, p := range data {
+			c := res.Labels[i]
+			counts[c]++
+			for j, v := range p {
+				next[c][j] += v
+			}
+		}
+		for c := range next {
+			if counts[c] == 0 {
+				continue
+			}
+			for j := range next[c] {
+				next[c][j] /= float64(counts[c])
+			}
+		}
+		reseed(data, res.Centroids, next, counts, dist)
+
+		shift := 0.0
+		for c := range next {
+			shift = max(shift, opts.Distance(res.Centroids[c], next[c]))
+			copy(res.Centroids[c], next[c])
+		}
+		if shift <= opts.Tol {
+			res.Converged = true
+			break
+		}
+	}
+
+	assign(data, res.Centroids, opts.Distance, res.Labels, dist)
+	for _, d := range dist {
+		res.Inertia += d
+	}
+	return res, nil
+}
+
+// seed picks k initial centroids with k-means++: each new centroid is drawn
+// with probability proportional to its distance from the nearest centroid
+// chosen so far.
+func seed(data [][]float64, k int, distance Distance, rng *rand.Rand) [][]float64 {
+	centroids := make([][]float64, 0, k)
+	centroids = append(centroids, append([]float64(nil), data[rng.Intn(len(data))]...))
+	nearest := make([]float64, len(data))
+	for i, p := range data {
+		nearest[i] = distance(p, centroids[0])
+	}
+	for len(centroids) < k {
+		var total float64
+		for _, d := range nearest {
+			total += d
+		}
+		pick := 0
+		if total > 0 {
+			r := rng.Float64() * total
+			for pick = 0; pick < len(data)-1; pick++ {
+				if r -= nearest[pick]; r < 0 {
+					break
+				}
+			}
+		} else {
+			pick = rng.Intn(len(data))
+		}
+		c := append([]float64(nil), data[pick]...)
+		centroids = append(centroids, c)
+		for i, p := range data {
+			nearest[i] = min(nearest[i], distance(p, c))
+		}
+	}
+	return centroids
+}
+
+// reseed moves the centroid of every empty cluster to the point furthest
+// from its own centroid, skipping points that already coincide with a
+// centroid so that no two clusters share one. If every point is taken,
+// which needs fewer than k distinct points, the cluster keeps its previous
+// centroid from prev.
+func reseed(data, prev, next [][]float64, counts []int, dist []float64) {
+	placed := make([]bool, len(next))
+	for c := range next {
+		placed[c] = counts[c] > 0
+	}
+	taken := func(p []float64) bool {
+		for c, centroid := range next {
+			if placed[c] && slices.Equal(p, centroid) {
+				return true
+			}
+		}
+		return false
+	}
+	for c := range next {
+		if placed[c] {
+			continue
+		}
+		far := -1
+		for i, p := range data {
+			if (far < 0 || dist[i] > dist[far]) && !taken(p) {
+				far = i
+			}
+		}
+		if far < 0 {
+			copy(next[c], prev[c])
+		} else {
+			copy(next[c], data[far])
+		}
+		placed[c] = true
+	}
+}
+
+// assign labels every point with its nearest centroid and records the
+// distance to it.
+func assign(data, centroids [][]float64, distance Distance, labels []int, dist []float64) {
+	for i, p := range data {
+		best, bestDist := 0, math.Inf(1)
+		for c, centroid := range centroids {
+			if d := distance(p, centroid); d < bestDist {
+				best, bestDist = c, d
+			}
+		}
+		labels[i], dist[i] = best, bestDist
+	}
+}
@@ -0,0 +1,215 @@
+package cluster
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestDistances(t *testing.T) {
+	a, b := []float64{1, 2, 3}, []float64{4, 6, 3}
+	for _, tc := range []struct {
+		name string
+		fn   Distance
+		a, b []float64
+		want float64
+	}{
+		{"SquaredEuclidean", SquaredEuclidean, a, b, 25},
+		{"Euclidean", Euclidean, a, b, 5},
+		{"Manhattan", Manhattan, a, b, 7},
+		{"Cosine parallel", Cosine, []float64{1, 1}, []float64{2, 2}, 0},
+		{"Cosine orthogonal", Cosine, []float64{1, 0}, []float64{0, 3}, 1},
+		{"Cosine zero", Cosine, []float64{0, 0}, []float64{1, 0}, 1},
+	} {
+		if got := tc.fn(tc.a, tc.b); math.Abs(got-tc.want) > 1e-12 {
+			t.Errorf("%s = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestKMeansErrors(t *testing.T) {
+	for _, tc := range []struct {
+		data [][]float64
+		k    int
+		want error
+	}{
+		{nil, 1, ErrNoData},
+		{[][]float64{{1, 2}, {3}}, 1, ErrRagged},
+		{[][]float64{{1}, {2}}, 0, ErrInvalidK},
+		{[][]float64{{1}, {2}}, 3, ErrInvalidK},
+	} {
+		if _, err := KMeans(tc.data, tc.k, KMeansOptions{}); !errors.Is(err, tc.want) {
+			t.Errorf("KMeans(%v, %d) error = %v, want %v", tc.data, tc.k, err, tc.want)
+		}
+	}
+}
+
+// TestKMeansSeparatedBlobs checks that well-separated blobs are recovered
+// exactly and that the result is a fixed point of Lloyd's algorithm, both
+// verified by brute force.
+func TestKMeansSeparatedBlobs(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	centers := [][]float64{{0, 0, 0}, {100, 0, 0}, {0, 100, 0}, {0, 0, 100}}
+	var data [][]float64
+	var truth []int
+	for i := 0; i < 400; i++ {
+		c := r.Intn(len(centers))
+		p := make([]float64, 3)
+		for j := range p {
+			p[j] = centers[c][j] + r.NormFloat64()
+		}
+		data = append(data, p)
+		truth = append(truth, c)
+	}
+	res, err := KMeans(data, len(centers), KMeansOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Converged {
+		t.Fatalf("did not converge in %d iterations", res.Iterations)
+	}
+
+	// Same partition as the generating centers, up to relabelling.
+	mapping := map[int]int{}
+	for i, l := range res.Labels {
+		if m, ok := mapping[truth[i]]; ok && m != l {
+			t.Fatalf("point %d split from its blob", i)
+		}
+		mapping[truth[i]] = l
+	}
+	if len(mapping) != len(centers) {
+		t.Fatalf("found %d clusters, want %d", len(mapping), len(centers))
+	}
+
+	inertia := 0.0
+	for i, p := range data {
+		best := 0
+		for c := range res.Centroids {
+			if SquaredEuclidean(p, res.Centroids[c]) < SquaredEuclidean(p, res.Centroids[best]) {
+				best = c
+			}
+		}
+		if best != res.Labels[i] {
+			t.Fatalf("point %d labelled %d, nearest centroid is %d", i, res.Labels[i], best)
+		}
+		inertia += SquaredEuclidean(p, res.Centroids[best])
+	}
+	if math.Abs(inertia-res.Inertia) > 1e-6*inertia {
+		t.Fatalf("Inertia = %v, want %v", res.Inertia, inertia)
+	}
+	for c, centroid := range res.Centroids {
+		mean := make([]float64, 3)
+		n := 0
+		for i, p := range data {
+			if res.Labels[i] == c {
+				n++
+				for j := range p {
+					mean[j] += p[j]
+				}
+			}
+		}
+		for j := range mean {
+			if mean[j] /= float64(n); math.Abs(mean[j]-centroid[j]) > 1e-6 {
+				t.Fatalf("centroid %d = %v, mean of its points is %v", c, centroid, mean)
+			}
+		}
+	}
+}
+
+func TestKMeansEachPointOwnCluster(t *testing.T) {
+	data := [][]float64{{0}, {5}, {9}, {20}}
+	res, err := KMeans(data, len(data), KMeansOptions{Distance: Manhattan})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Inertia != 0 {
+		t.Fatalf("Inertia = %v, want 0", res.Inertia)
+	}
+	seen := map[int]bool{}
+	for _, l := range res.Labels {
+		seen[l] = true
+	}
+	if len(seen) != len(data) {
+		t.Fatalf("Labels = %v, want all distinct", res.Labels)
+	}
+}
+
+func TestReseedSkipsDuplicates(t *testing.T) {
+	data := [][]float64{{0, 0}, {10, 10}, {10, 10}, {5, 5}}
+	prev := [][]float64{{0, 0}, {-1, -1}, {-2, -2}}
+	next := [][]float64{{0, 0}, {0, 0}, {0, 0}}
+	counts := []int{4, 0, 0}
+	dist := []float64{0, 200, 200, 50}
+	reseed(data, prev, next, counts, dist)
+	want := [][]float64{{0, 0}, {10, 10}, {5, 5}}
+	for c := range want {
+		if !slices.Equal(next[c], want[c]) {
+			t.Fatalf("reseeded centroids = %v, want %v", next, want)
+		}
+	}
+
+	// With every point taken, an empty cluster stays where it was.
+	data = [][]float64{{1, 1}, {1, 1}}
+	next = [][]float64{{1, 1}, {0, 0}}
+	reseed(data, prev, next, []int{2, 0}, []float64{0, 0})
+	if !slices.Equal(next[1], prev[1]) {
+		t.Fatalf("centroid with no free point = %v, want %v", next[1], prev[1])
+	}
+}
+
+func TestKMeansDuplicatePoints(t *testing.T) {
+	var data [][]float64
+	for _, p := range [][]float64{{0, 0}, {0, 1}, {1, 0}, {1, 1}} {
+		for range 10 {
+			data = append(data, p)
+		}
+	}
+	for seed := int64(1); seed <= 20; seed++ {
+		res, err := KMeans(data, 4, KMeansOptions{Rand: rand.New(rand.NewSource(seed))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for a := range res.Centroids {
+			for b := a + 1; b < len(res.Centroids); b++ {
+				if slices.Equal(res.Centroids[a], res.Centroids[b]) {
+					t.Fatalf("seed %d: clusters %d and %d share centroid %v", seed, a, b, res.Centroids[a])
+				}
+			}
+		}
+		if res.Inertia != 0 {
+			t.Fatalf("seed %d: Inertia = %v, want 0", seed, res.Inertia)
+		}
+	}
+}
+
+func TestKMeansStoppedEarly(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	data := make([][]float64, 300)
+	for i := range data {
+		data[i] = []float64{r.NormFloat64(), r.NormFloat64()}
+	}
+	res, err := KMeans(data, 8, KMeansOptions{MaxIter: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Converged || res.Iterations != 1 {
+		t.Fatalf("Converged = %v after %d iterations, want a stop at MaxIter", res.Converged, res.Iterations)
+	}
+	// Labels always name the nearest centroid, and Inertia sums exactly
+	// those distances.
+	var inertia float64
+	for i, p := range data {
+		d := SquaredEuclidean(p, res.Centroids[res.Labels[i]])
+		for c := range res.Centroids {
+			if SquaredEuclidean(p, res.Centroids[c]) < d {
+				t.Fatalf("point %d labelled %d but nearer to %d", i, res.Labels[i], c)
+			}
+		}
+		inertia += d
+	}
+	if math.Abs(inertia-res.Inertia) > 1e-9 {
+		t.Fatalf("Inertia = %v, want %v", res.Inertia, inertia)
+	}
+}
@@ -0,0 +1,262 @@
+// Package doublearray implements a static double-array trie.
+//
+// A double-array trie encodes every transition s --c--> t of a trie as
+// t = base[s] + code(c) with check[t] == s, so a lookup costs two array
+// reads per input byte and no pointer chasing. The arrays are kept in a
+// flat little-endian byte layout that can be written to disk and used
+// directly from a memory-mapped file, which suits tokenizers and
+// dictionary-lookup hot paths that load large dictionaries at start-up.
+package doublearray
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+	"unsafe"
+)
+
+var (
+	// ErrUnsorted is returned by Build when keys are not in ascending order.
+	ErrUnsorted = errors.New("doublearray: keys are not sorted")
+	// ErrDuplicateKey is returned by Build when a key occurs twice.
+	ErrDuplicateKey = errors.New("doublearray: duplicate key")
+	// ErrValueCount is returned by Build when values and keys differ in
+	// length.
+	ErrValueCount = errors.New("doublearray: values and keys differ in length")
+	// ErrFormat is returned by Load for data that is not a serialized trie.
+	ErrFormat = errors.New("doublearray: invalid data")
+)
+
+var magic = [4]byte{'D', 'A', 'T', '1'}
+
+const (
+	headerSize = 8 // magic + unit count
+	unitSize   = 8 // base int32 + check int32
+	free       = -1
+)
+
+// Trie is an immutable map from strings to int32 values.
+type Trie struct {
+	data []byte
+	n    int // number of units
+}
+
+// Build constructs a trie mapping keys[i] to values[i]. Keys must be sorted
+// in ascending order and unique. If values is nil, each key maps to its
+// index in keys.
+func Build(keys []string, values []int32) (*Trie, error) {
+	if values != nil && len(values) != len(keys) {
+		return nil, ErrValueCount
+	}
+	for i := 1; i < len(keys); i++ {
+		switch {
+		case keys[i] < keys[i-1]:
+			return nil, ErrUnsorted
+		case keys[i] == keys[i-1]:
+			return nil, ErrDuplicateKey
+		}
+	}
+	b := &builder{keys: keys, values: values}
+	b.grow(256)
+	b.check[0] = 0 // the root occupies slot 0
+	if len(keys) > 0 {
+		b.insert(0, 0, len(keys), 0)
+	}
+	return b.finish(), nil
+}
+
+// Load returns a trie backed by data, which must have been produced by
+// Bytes or WriteTo. data is used in place, not copied, and must not be
+// modified while the trie is in use.
+func Load(data []byte) (*Trie, error) {
+	if len(data) < headerSize || [4]byte(data[:4]) != magic {
+		return nil, ErrFormat
+	}
+	n := int(binary.LittleEndian.Uint32(data[4:]))
+	if n == 0 || len(data) != headerSize+n*unitSize {
+		return nil, ErrFormat
+	}
+	return &Trie{data: data, n: n}, nil
+}
+
+// Bytes returns the serialized trie. The slice is shared with t and must
+// not be modified.
+func (t *Trie) Bytes() []byte {
+	return t.data
+}
+
+// WriteTo writes the serialized trie to w.
+func (t *Trie) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(t.data)
+	return int64(n), err
+}
+
+// Units returns the number of array slots, a measure of the trie's size.
+func (t *Trie) Units() int {
+	return t.n
+}
+
+// Sizeof returns the approximate heap usage of the trie in bytes. For a
+// trie created by Load this includes the caller's buffer, which the trie
+// shares rather than copies.
+func (t *Trie) Sizeof() int {
+	return int(unsafe.Sizeof(*t)) + cap(t.data)
+}
+
+func (t *Trie) base(s int) int32 {
+	return int32(binary.LittleEndian.Uint32(t.data[headerSize+s*unitSize:]))
+}
+
+func (t *Trie) check(s int) int32 {
+	return int32(binary.LittleEndian.Uint32(t.data[headerSize+s*unitSize+4:]))
+}
+
+// next follows the transition from s by code, returning -1 if there is
+// none.
+func (t *Trie) next(s int, code int) int {
+	u := int(t.base(s)) + code
+	if u <= 0 || u >= t.n || t.check(u) != int32(s) {
+		return -1
+	}
+	return u
+}
+
+// Get returns the value stored for key. The boolean is false if key is
+// absent.
+func (t *Trie) Get(key string) (int32, bool) {
+	s := 0
+	for i := 0; i < len(key); i++ {
+		if s = t.next(s, int(key[i])+1); s < 0 {
+			return 0, false
+		}
+	}
+	if leaf := t.next(s, 0); leaf >= 0 {
+		return t.base(leaf), true
+	}
+	return 0, false
+}
+
+// Contains reports whether key is in the trie.
+func (t *Trie) Contains(key string) bool {
+	_, ok := t.Get(key)
+	return ok
+}
+
+// CommonPrefixSearch calls fn with the length and value of every key that
+// is a prefix of s, shortest first, until fn returns false.
+func (t *Trie) CommonPrefixSearch(s string, fn func(length int, value int32) bool) {
+	node := 0
+	for i := 0; ; i++ {
+		if leaf := t.next(node, 0); leaf >= 0 {
+			if !fn(i, t.base(leaf)) {
+				return
+			}
+		}
+		if i == len(s) {
+			return
+		}
+		if node = t.next(node, int(s[i])+1); node < 0 {
+			return
+		}
+	}
+}
+
+type builder struct {
+	keys   []string
+	values []int32
+	base   []int32
+	check  []int32
+	hint   int // every slot below hint is occupied
+}
+
+func (b *builder) grow(n int) {
+	for len(b.check) < n {
+		b.base = append(b.base, 0)
+		b.check = append(b.check, free)
+	}
+}
+
+// insert places the children of node s, which represents keys[lo:hi] at
+// the given depth, and recurses into them.
+func (b *builder) insert(s, lo, hi, depth int) {
+	// Codes: 0 terminates a key, c+1 continues with byte c.
+	type child struct{ code, lo, hi int }
+	var children []child
+	for i := lo; i < hi; {
+		if len(b.keys[i]) == depth {
+			children = append(children, child{0, i, i + 1})
+			i++
+			continue
+		}
+		c := b.keys[i][depth]
+		j := i + 1 + sort.Search(hi-i-1, func(k int) bool { return b.keys[i+1+k][depth] != c })
+		children = append(children, child{int(c) + 1, i, j})
+		i = j
+	}
+
+	codes := make([]int, len(children))
+	for i, c := range children {
+		codes[i] = c.code
+	}
+	base := b.findBase(codes)
+	b.base[s] = int32(base)
+	for _, c := range children {
+		b.check[base+c.code] = int32(s)
+	}
+	for _, c := range children {
+		u := base + c.code
+		if c.code == 0 {
+			v := int32(c.lo)
+			if b.values != nil {
+				v = b.values[c.lo]
+			}
+			b.base[u] = v
+			continue
+		}
+		b.insert(u, c.lo, c.hi, depth+1)
+	}
+}
+
+// findBase returns the smallest base such that base+code is free for
+// every code, growing the arrays as needed. codes must be ascending.
+func (b *builder) findBase(codes []int) int {
+	for b.hint < len(b.check) && b.check[b.hint] != free {
+		b.hint++
+	}
+	first, last := codes[0], codes[len(codes)-1]
+	for pos := max(b.hint, first+1); ; pos++ {
+		base := pos - first
+		b.grow(base + last + 1)
+		if b.check[pos] != free {
+			continue
+		}
+		fits := true
+		for _, c := range codes[1:] {
+			if b.check[base+c] != free {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			return base
+		}
+	}
+}
+
+func (b *builder) finish() *Trie {
+	// Trim unused trailing slots.
+	n := len(b.check)
+	for n > 1 && b.check[n-1] == free {
+		n--
+	}
+	data := make([]byte, headerSize+n*unitSize)
+	copy(data, magic[:])
+	binary.LittleEndian.PutUint32(data[4:], uint32(n))
+	for i := 0; i < n; i++ {
+		off := headerSize + i*unitSize
+		binary.LittleEndian.PutUint32(data[off:], uint32(b.base[i]))
+		binary.LittleEndian.PutUint32(data[off+4:], uint32(b.check[i]))
+	}
+	return &Trie{data: data, n: n}
+}
@@ -0,0 +1,162 @@
+package doublearray
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+	"unsafe"
+)
+
+func randomKey(r *rand.Rand) string {
+	b := make([]byte, r.Intn(7))
+	for i := range b {
+		b[i] = "ab\x00\xffz"[r.Intn(5)]
+	}
+	return string(b)
+}
+
+func TestAgainstMap(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	model := map[string]int32{}
+	for len(model) < 1500 {
+		model[randomKey(r)] = r.Int31() - 1<<30
+	}
+	keys := make([]string, 0, len(model))
+	for k := range model {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	values := make([]int32, len(keys))
+	for i, k := range keys {
+		values[i] = model[k]
+	}
+	built, err := Build(keys, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := built.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tr := range []*Trie{built, loaded} {
+		for i := 0; i < 3000; i++ {
+			q := randomKey(r)
+			want, wantOK := model[q]
+			if got, ok := tr.Get(q); ok != wantOK || got != want {
+				t.Fatalf("Get(%q) = %d, %v; want %d, %v", q, got, ok, want, wantOK)
+			}
+			var lengths, wantLengths []int
+			tr.CommonPrefixSearch(q, func(n int, v int32) bool {
+				if v != model[q[:n]] {
+					t.Fatalf("CommonPrefixSearch(%q) value %d for length %d", q, v, n)
+				}
+				lengths = append(lengths, n)
+				return true
+			})
+			for n := 0; n <= len(q); n++ {
+				if _, ok := model[q[:n]]; ok {
+					wantLengths = append(wantLengths, n)
+				}
+			}
+			if !slices.Equal(lengths, wantLengths) {
+				t.Fatalf("CommonPrefixSearch(%q) = %v, want %v", q, lengths, wantLengths)
+			}
+		}
+	}
+}
+
+func TestDefaultValuesAreIndices(t *testing.T) {
+	keys := []string{"", "a", "ab", "b"}
+	tr, err := Build(keys, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, k := range keys {
+		if v, ok := tr.Get(k); !ok || v != int32(i) {
+			t.Errorf("Get(%q) = %d, %v; want %d", k, v, ok, i)
+		}
+	}
+	if tr.Contains("c") || tr.Contains("abc") {
+		t.Error("Contains reported an absent key")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, tc := range []struct {
+		keys   []string
+		values []int32
+		want   error
+	}{
+		{[]string{"b", "a"}, nil, ErrUnsorted},
+		{[]string{"a", "a"}, nil, ErrDuplicateKey},
+		{[]string{"a"}, []int32{1, 2}, ErrValueCount},
+	} {
+		if _, err := Build(tc.keys, tc.values); !errors.Is(err, tc.want) {
+			t.Errorf("Build(%q) error = %v, want %v", tc.keys, err, tc.want)
+		}
+	}
+	tr, err := Build([]string{"x"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	good := tr.Bytes()
+	for name, data := range map[string][]byte{
+		"empty":     nil,
+		"magic":     append([]byte("XXXX"), good[4:]...),
+		"truncated": good[:len(good)-1],
+		"zero":      append(slices.Clone(good[:4]), 0, 0, 0, 0),
+	} {
+		if _, err := Load(data); !errors.Is(err, ErrFormat) {
+			t.Errorf("Load(%s) error = %v, want ErrFormat", name, err)
+		}
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	tr, err := Build(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Contains("") || tr.Units() != 1 {
+		t.Fatalf("empty trie: Contains(\"\") = true or Units() = %d", tr.Units())
+	}
+	if _, err := Load(tr.Bytes()); err != nil {
+		t.Fatalf("Load of empty trie: %v", err)
+	}
+}
+
+func TestSizeof(t *testing.T) {
+	keys := make([]string, 2000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%016x", uint64(i)*0x9e3779b97f4a7c15)
+	}
+	slices.Sort(keys)
+	tr, err := Build(keys, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A built trie is exactly its serialized form: the header and one
+	// base/check pair per unit.
+	header := int(unsafe.Sizeof(*tr))
+	if got, want := tr.Sizeof(), header+headerSize+tr.Units()*unitSize; got != want {
+		t.Fatalf("Sizeof() = %d for %d units, want %d", got, tr.Units(), want)
+	}
+	// A loaded trie shares the caller's buffer, spare capacity included.
+	buf := make([]byte, len(tr.Bytes()), len(tr.Bytes())+4096)
+	copy(buf, tr.Bytes())
+	loaded, err := Load(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := loaded.Sizeof(), header+cap(buf); got != want {
+		t.Fatalf("Sizeof() of a loaded trie = %d, want %d", got, want)
+	}
+}
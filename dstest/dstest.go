@@ -0,0 +1,265 @@
+// Package dstest checks container implementations by running random
+// operation sequences against them and against a naive reference model,
+// reporting the first point at which the two disagree.
+//
+// A container is connected through a small adapter interface, Map or
+// OrderedMap, which most key-value structures satisfy directly or with a
+// few lines of glue. Failures carry the seed and the full operation log so
+// that a failing sequence can be replayed.
+package dstest
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+)
+
+// Map is the adapter interface for key-value containers.
+type Map[K, V any] interface {
+	Put(key K, value V)
+	Get(key K) (V, bool)
+	Delete(key K) bool
+	Len() int
+}
+
+// OrderedMap is a Map that can also list its keys in ascending order.
+type OrderedMap[K, V any] interface {
+	Put(key K, value V)
+	Get(key K) (V, bool)
+	Delete(key K) bool
+	Len() int
+	Keys() []K
+}
+
+// Kind identifies an operation.
+type Kind int
+
+// The operations a sequence is drawn from.
+const (
+	Put Kind = iota
+	Get
+	Delete
+	Len
+	Keys
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Put:
+		return "Put"
+	case Get:
+		return "Get"
+	case Delete:
+		return "Delete"
+	case Len:
+		return "Len"
+	case Keys:
+		return "Keys"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Op is one step of a generated sequence.
+type Op[K, V any] struct {
+	Kind  Kind
+	Key   K
+	Value V
+}
+
+func (o Op[K, V]) String() string {
+	switch o.Kind {
+	case Put:
+		return fmt.Sprintf("Put(%v, %v)", o.Key, o.Value)
+	case Get, Delete:
+		return fmt.Sprintf("%v(%v)", o.Kind, o.Key)
+	}
+	return o.Kind.String() + "()"
+}
+
+// Config controls sequence generation.
+type Config[K, V any] struct {
+	// Ops is the number of operations to run. Defaults to 1000.
+	Ops int
+	// Seed seeds the generator; runs with equal seeds are identical.
+	Seed int64
+	// Key draws a key. It is required. Drawing from a small domain makes
+	// overwrites and deletions of present keys likely.
+	Key func(r *rand.Rand) K
+	// Value draws a value. If nil every value is the zero value.
+	Value func(r *rand.Rand) V
+	// Weights gives the relative frequency of Put, Get, Delete, Len and
+	// Keys, indexed by Kind. Defaults to 4, 4, 2, 1, 1. Keys is only
+	// generated by the ordered checks.
+	Weights [5]int
+}
+
+// IntKeys returns a Config.Key function drawing integers from [0, n).
+func IntKeys(n int) func(r *rand.Rand) int {
+	return func(r *rand.Rand) int { return r.Intn(n) }
+}
+
+// Failure describes a disagreement between a container and the reference
+// model.
+type Failure[K, V any] struct {
+	Seed int64
+	// Ops is the sequence up to and including the failing operation.
+	Ops []Op[K, V]
+	Msg string
+}
+
+func (f *Failure[K, V]) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "dstest: step %d: %s (seed %d)", len(f.Ops)-1, f.Msg, f.Seed)
+	for i, op := range f.Ops {
+		fmt.Fprintf(&b, "\n\t%d: %v", i, op)
+	}
+	return b.String()
+}
+
+// CheckMap runs a random sequence against m, which must start empty, and a
+// reference model that compares keys with ==. It returns a *Failure for
+// the first mismatch, or nil.
+func CheckMap[K, V comparable](m Map[K, V], cfg Config[K, V]) error {
+	ref := &model[K, V]{equal: func(a, b K) bool { return a == b }}
+	return run(m, ref, cfg)
+}
+
+// CheckOrdered is CheckOrderedFunc with cmp.Compare.
+func CheckOrdered[K cmp.Ordered, V comparable](m OrderedMap[K, V], cfg Config[K, V]) error {
+	return CheckOrderedFunc(m, cmp.Compare[K], cfg)
+}
+
+// CheckOrderedFunc runs a random sequence against m, which must start empty
+// and order keys by compare, and a reference model that keeps keys in a
+// sorted slice. Keys equal under compare are treated as the same key, so
+// the check also validates custom comparators against the container.
+func CheckOrderedFunc[K any, V comparable](m OrderedMap[K, V], compare func(a, b K) int, cfg Config[K, V]) error {
+	return run(m, &model[K, V]{compare: compare}, cfg)
+}
+
+// model is the reference implementation: a slice of entries kept sorted by
+// compare, or in insertion order when compare is nil.
+type model[K, V any] struct {
+	keys    []K
+	values  []V
+	compare func(a, b K) int
+	equal   func(a, b K) bool
+}
+
+func (m *model[K, V]) find(key K) (int, bool) {
+	if m.compare != nil {
+		return slices.BinarySearchFunc(m.keys, key, m.compare)
+	}
+	for i, k := range m.keys {
+		if m.equal(k, key) {
+			return i, true
+		}
+	}
+	return len(m.keys), false
+}
+
+func (m *model[K, V]) put(key K, value V) {
+	i, ok := m.find(key)
+	if ok {
+		m.values[i] = value
+		return
+	}
+	m.keys = slices.Insert(m.keys, i, key)
+	m.values = slices.Insert(m.values, i, value)
+}
+
+func (m *model[K, V]) get(key K) (V, bool) {
+	if i, ok := m.find(key); ok {
+		return m.values[i], true
+	}
+	var zero V
+	return zero, false
+}
+
+func (m *model[K, V]) delete(key K) bool {
+	i, ok := m.find(key)
+	if ok {
+		m.keys = slices.Delete(m.keys, i, i+1)
+		m.values = slices.Delete(m.values, i, i+1)
+	}
+	return ok
+}
+
+func run[K any, V comparable](m Map[K, V], ref *model[K, V], cfg Config[K, V]) error {
+	if cfg.Key == nil {
+		panic("dstest: Config.Key is required")
+	}
+	if cfg.Ops <= 0 {
+		cfg.Ops = 1000
+	}
+	if cfg.Weights == [5]int{} {
+		cfg.Weights = [5]int{4, 4, 2, 1, 1}
+	}
+	if ref.compare == nil {
+		cfg.Weights[Keys] = 0
+	}
+	total := 0
+	for _, w := range cfg.Weights {
+		total += max(w, 0)
+	}
+	if total == 0 {
+		panic("dstest: all operation weights are zero")
+	}
+
+	r := rand.New(rand.NewSource(cfg.Seed))
+	ops := make([]Op[K, V], 0, cfg.Ops)
+	fail := func(format string, args ...any) error {
+		return &Failure[K, V]{Seed: cfg.Seed, Ops: ops, Msg: fmt.Sprintf(format, args...)}
+	}
+
+	for range cfg.Ops {
+		op := Op[K, V]{Kind: pick(r, cfg.Weights, total)}
+		if op.Kind == Put || op.Kind == Get || op.Kind == Delete {
+			op.Key = cfg.Key(r)
+		}
+		if op.Kind == Put && cfg.Value != nil {
+			op.Value = cfg.Value(r)
+		}
+		ops = append(ops, op)
+
+		switch op.Kind {
+		case Put:
+			m.Put(op.Key, op.Value)
+			ref.put(op.Key, op.Value)
+		case Get:
+			got, gotOK := m.Get(op.Key)
+			want, wantOK := ref.get(op.Key)
+			if got != want || gotOK != wantOK {
+				return fail("got (%v, %v), want (%v, %v)", got, gotOK, want, wantOK)
+			}
+		case Delete:
+			if got, want := m.Delete(op.Key), ref.delete(op.Key); got != want {
+				return fail("got %v, want %v", got, want)
+			}
+		case Len:
+			if got, want := m.Len(), len(ref.keys); got != want {
+				return fail("got %d, want %d", got, want)
+			}
+		case Keys:
+			got := m.(OrderedMap[K, V]).Keys()
+			if !slices.EqualFunc(got, ref.keys, func(a, b K) bool { return ref.compare(a, b) == 0 }) {
+				return fail("got %v, want %v", got, ref.keys)
+			}
+		}
+	}
+	return nil
+}
+
+func pick(r *rand.Rand, weights [5]int, total int) Kind {
+	n := r.Intn(total)
+	for k, w := range weights {
+		w = max(w, 0)
+		if n < w {
+			return Kind(k)
+		}
+		n -= w
+	}
+	panic("unreachable")
+}
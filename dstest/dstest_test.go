@@ -0,0 +1,121 @@
+package dstest
+
+import (
+	"errors"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// goMap adapts a built-in map, optionally with an injected bug.
+type goMap struct {
+	m       map[int]int
+	forgetN int // when positive, every forgetN-th Put is dropped
+	puts    int
+}
+
+func newGoMap() *goMap { return &goMap{m: map[int]int{}} }
+
+func (g *goMap) Put(k, v int) {
+	g.puts++
+	if g.forgetN > 0 && g.puts%g.forgetN == 0 {
+		return
+	}
+	g.m[k] = v
+}
+
+func (g *goMap) Get(k int) (int, bool) {
+	v, ok := g.m[k]
+	return v, ok
+}
+
+func (g *goMap) Delete(k int) bool {
+	_, ok := g.m[k]
+	delete(g.m, k)
+	return ok
+}
+
+func (g *goMap) Len() int { return len(g.m) }
+
+func (g *goMap) Keys() []int {
+	keys := make([]int, 0, len(g.m))
+	for k := range g.m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func values(r *rand.Rand) int { return r.Intn(100) }
+
+func TestCorrectMapPasses(t *testing.T) {
+	for seed := range int64(20) {
+		cfg := Config[int, int]{Seed: seed, Key: IntKeys(30), Value: values}
+		if err := CheckMap[int, int](newGoMap(), cfg); err != nil {
+			t.Fatal(err)
+		}
+		if err := CheckOrdered[int, int](newGoMap(), cfg); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBuggyMapFailsReproducibly(t *testing.T) {
+	cfg := Config[int, int]{Seed: 7, Key: IntKeys(10), Value: values}
+	err := CheckOrdered[int, int](&goMap{m: map[int]int{}, forgetN: 13}, cfg)
+	var f *Failure[int, int]
+	if !errors.As(err, &f) {
+		t.Fatalf("CheckOrdered error = %v, want *Failure", err)
+	}
+	if f.Seed != 7 || len(f.Ops) == 0 || !strings.Contains(err.Error(), "seed 7") {
+		t.Fatalf("Failure = %+v", f)
+	}
+	again := CheckOrdered[int, int](&goMap{m: map[int]int{}, forgetN: 13}, cfg)
+	if again == nil || again.Error() != err.Error() {
+		t.Fatalf("replay with the same seed gave %v, want %v", again, err)
+	}
+}
+
+func TestCheckOrderedFuncComparator(t *testing.T) {
+	// Keys compared modulo 10 collapse in the model, which the plain map
+	// does not do, so the check must report a mismatch.
+	mod := func(a, b int) int { return a%10 - b%10 }
+	cfg := Config[int, int]{Seed: 1, Key: IntKeys(100), Value: values}
+	if err := CheckOrderedFunc[int, int](newGoMap(), mod, cfg); err == nil {
+		t.Fatal("CheckOrderedFunc accepted a map that ignores the comparator")
+	}
+}
+
+func TestWeights(t *testing.T) {
+	cfg := Config[int, int]{Key: IntKeys(5), Weights: [5]int{1, 0, 0, 0, 0}, Ops: 50}
+	m := newGoMap()
+	if err := CheckMap[int, int](m, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if m.puts != 50 {
+		t.Fatalf("ran %d puts, want 50", m.puts)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("zero weights did not panic")
+		}
+	}()
+	CheckMap[int, int](newGoMap(), Config[int, int]{Key: IntKeys(5), Weights: [5]int{0, 0, 0, 0, 1}})
+}
+
+func TestOpString(t *testing.T) {
+	for _, tc := range []struct {
+		op   Op[int, string]
+		want string
+	}{
+		{Op[int, string]{Put, 1, "a"}, "Put(1, a)"},
+		{Op[int, string]{Kind: Get, Key: 2}, "Get(2)"},
+		{Op[int, string]{Kind: Delete, Key: 3}, "Delete(3)"},
+		{Op[int, string]{Kind: Keys}, "Keys()"},
+	} {
+		if got := tc.op.String(); got != tc.want {
+			t.Errorf("String() = %q, want %q", got, tc.want)
+		}
+	}
+}
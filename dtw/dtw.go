@@ -0,0 +1,157 @@
+// Package dtw computes dynamic time warping distances between sequences.
+//
+// Dynamic time warping aligns two sequences by stretching either of them in
+// time so that the summed cost of matched elements is minimal. An optional
+// Sakoe-Chiba band limits how far the alignment may stray from the
+// diagonal, which both speeds the computation up, to O(n·w), and prevents
+// pathological warps.
+package dtw
+
+import "math"
+
+// Options configures an alignment. The zero value computes an
+// unconstrained alignment with its path.
+type Options struct {
+	// Window is the Sakoe-Chiba band radius: element i of the first
+	// sequence may only be matched with elements j where |i-j| ≤ Window.
+	// It is widened to the length difference of the sequences when
+	// smaller, so an alignment always exists. Zero or negative means
+	// unconstrained.
+	Window int
+	// NoPath skips recovering the alignment path, which lets the distance
+	// be computed in O(w) memory instead of O(n·w).
+	NoPath bool
+}
+
+// Pair matches element I of the first sequence with element J of the
+// second.
+type Pair struct {
+	I, J int
+}
+
+// Result is the outcome of an alignment.
+type Result struct {
+	// Distance is the summed cost along the optimal alignment. It is +Inf
+	// when exactly one of the sequences is empty.
+	Distance float64
+	// Path lists the matched pairs from (0, 0) to (n-1, m-1). It is nil
+	// when Options.NoPath is set.
+	Path []Pair
+}
+
+// Align aligns two scalar sequences using the absolute difference as the
+// cost of matching two elements.
+func Align(a, b []float64, opts Options) Result {
+	return AlignFunc(len(a), len(b), func(i, j int) float64 {
+		return math.Abs(a[i] - b[j])
+	}, opts)
+}
+
+// AlignRows aligns two multivariate sequences, each element being a row of
+// equal dimension, using the Euclidean distance between rows as the cost.
+func AlignRows(a, b [][]float64, opts Options) Result {
+	return AlignFunc(len(a), len(b), func(i, j int) float64 {
+		var sum float64
+		for k := range a[i] {
+			d := a[i][k] - b[j][k]
+			sum += d * d
+		}
+		return math.Sqrt(sum)
+	}, opts)
+}
+
+// AlignFunc aligns sequences of length n and m given the cost of matching
+// element i of the first with element j of the second.
+func AlignFunc(n, m int, cost func(i, j int) float64, opts Options) Result {
+	switch {
+	case n == 0 && m == 0:
+		return Result{}
+	case n == 0 || m == 0:
+		return Result{Distance: math.Inf(1)}
+	}
+
+	w := opts.Window
+	if w <= 0 {
+		w = max(n, m)
+	}
+	w = max(w, abs(n-m))
+	band := func(i int) (lo, hi int) {
+		return max(0, i-w), min(m-1, i+w)
+	}
+
+	// Each row of the accumulated-cost table only stores the columns in its
+	// band; off[i] is where row i starts in acc.
+	rows := 2
+	if !opts.NoPath {
+		rows = n
+	}
+	off := make([]int, rows+1)
+	for i := 0; i < rows; i++ {
+		lo, hi := band(i)
+		if opts.NoPath {
+			lo, hi = 0, min(m-1, 2*w)
+		}
+		off[i+1] = off[i] + hi - lo + 1
+	}
+	acc := make([]float64, off[rows])
+
+	at := func(i, j int) float64 {
+		if i < 0 || j < 0 {
+			return math.Inf(1)
+		}
+		lo, hi := band(i)
+		if j < lo || j > hi {
+			return math.Inf(1)
+		}
+		r := i
+		if opts.NoPath {
+			r = i & 1
+		}
+		return acc[off[r]+j-lo]
+	}
+	for i := 0; i < n; i++ {
+		lo, hi := band(i)
+		r := i
+		if opts.NoPath {
+			r = i & 1
+		}
+		for j := lo; j <= hi; j++ {
+			best := 0.0
+			if i > 0 || j > 0 {
+				best = min(at(i-1, j), at(i, j-1), at(i-1, j-1))
+			}
+			acc[off[r]+j-lo] = cost(i, j) + best
+		}
+	}
+
+	res := Result{Distance: at(n-1, m-1)}
+	if opts.NoPath {
+		return res
+	}
+
+	i, j := n-1, m-1
+	res.Path = append(res.Path, Pair{i, j})
+	for i > 0 || j > 0 {
+		diag, up, left := at(i-1, j-1), at(i-1, j), at(i, j-1)
+		switch {
+		case diag <= up && diag <= left:
+			i, j = i-1, j-1
+		case up <= left:
+			i--
+		default:
+			j--
+		}
+		res.Path = append(res.Path, Pair{i, j})
+	}
+	for l, r := 0, len(res.Path)-1; l < r; l, r = l+1, r-1 {
+		res.Path[l], res.Path[r] = res.Path[r], res.Path[l]
+	}
+	return res
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,99 @@
+package dtw
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// naive fills the full n x m table, treating cells outside the band as
+// unreachable.
+func naive(a, b []float64, w int) float64 {
+	n, m := len(a), len(b)
+	if w <= 0 {
+		w = max(n, m)
+	}
+	w = max(w, abs(n-m))
+	acc := make([][]float64, n+1)
+	for i := range acc {
+		acc[i] = make([]float64, m+1)
+		for j := range acc[i] {
+			acc[i][j] = math.Inf(1)
+		}
+	}
+	acc[0][0] = 0
+	for i := 1; i <= n; i++ {
+		for j := 1; j <= m; j++ {
+			if abs(i-j) <= w {
+				acc[i][j] = math.Abs(a[i-1]-b[j-1]) + min(acc[i-1][j], acc[i][j-1], acc[i-1][j-1])
+			}
+		}
+	}
+	return acc[n][m]
+}
+
+func randomSeq(r *rand.Rand, n int) []float64 {
+	s := make([]float64, n)
+	for i := range s {
+		s[i] = float64(r.Intn(20))
+	}
+	return s
+}
+
+func TestAlignAgainstFullTable(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 1000; trial++ {
+		a, b := randomSeq(r, 1+r.Intn(15)), randomSeq(r, 1+r.Intn(15))
+		w := r.Intn(6)
+		want := naive(a, b, w)
+		res := Align(a, b, Options{Window: w})
+		if res.Distance != want {
+			t.Fatalf("Align(%v, %v, w=%d) = %v, want %v", a, b, w, res.Distance, want)
+		}
+		if d := Align(a, b, Options{Window: w, NoPath: true}).Distance; d != want {
+			t.Fatalf("Align NoPath(%v, %v, w=%d) = %v, want %v", a, b, w, d, want)
+		}
+
+		p := res.Path
+		if p[0] != (Pair{0, 0}) || p[len(p)-1] != (Pair{len(a) - 1, len(b) - 1}) {
+			t.Fatalf("path %v does not span both sequences", p)
+		}
+		sum := math.Abs(a[0] - b[0])
+		for k := 1; k < len(p); k++ {
+			di, dj := p[k].I-p[k-1].I, p[k].J-p[k-1].J
+			if di < 0 || dj < 0 || di > 1 || dj > 1 || di+dj == 0 {
+				t.Fatalf("path %v has invalid step at %d", p, k)
+			}
+			sum += math.Abs(a[p[k].I] - b[p[k].J])
+		}
+		if sum != want {
+			t.Fatalf("path %v costs %v, want %v", p, sum, want)
+		}
+	}
+}
+
+func TestAlignRows(t *testing.T) {
+	a := [][]float64{{0, 0}, {3, 4}, {3, 4}}
+	b := [][]float64{{0, 0}, {3, 4}}
+	if res := AlignRows(a, b, Options{}); res.Distance != 0 || len(res.Path) != 3 {
+		t.Fatalf("AlignRows = %+v, want distance 0 over 3 pairs", res)
+	}
+	if res := AlignRows(a[:1], b[1:], Options{}); res.Distance != 5 {
+		t.Fatalf("AlignRows distance = %v, want 5", res.Distance)
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	for _, tc := range []struct {
+		a, b []float64
+		want float64
+	}{
+		{nil, nil, 0},
+		{nil, []float64{1}, math.Inf(1)},
+		{[]float64{1}, nil, math.Inf(1)},
+	} {
+		if res := Align(tc.a, tc.b, Options{}); res.Distance != tc.want || res.Path != nil {
+			t.Errorf("Align(%v, %v) = %+v, want %v", tc.a, tc.b, res, tc.want)
+		}
+	}
+}
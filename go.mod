@@ -0,0 +1,3 @@
+module github.com/anon-org/ds
+
+go 1.23
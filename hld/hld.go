@@ -0,0 +1,282 @@
+// Package hld implements heavy-light decomposition of rooted forests.
+//
+// The decomposition lays vertices out so that every root-to-leaf path
+// crosses O(log n) heavy chains and every chain, as well as every subtree,
+// occupies a contiguous range of positions. Path and subtree operations
+// therefore reduce to a handful of range operations over an array, which
+// PathQuery serves with a segment tree in O(log² n). LazyPathQuery adds
+// updates over whole paths and subtrees, such as adding a constant to
+// every vertex on a path, in the same time bound.
+package hld
+
+import (
+	"errors"
+	"unsafe"
+)
+
+// None marks the parent of a root.
+const None = -1
+
+const intSize = int(unsafe.Sizeof(0))
+
+var (
+	// ErrInvalidParent is returned when a parent index is outside [0, n)
+	// and not None.
+	ErrInvalidParent = errors.New("hld: parent index out of range")
+	// ErrCycle is returned when the parent slice does not describe a
+	// forest.
+	ErrCycle = errors.New("hld: parent links contain a cycle")
+)
+
+// Range is a half-open interval [Lo, Hi) of positions.
+type Range struct {
+	Lo, Hi int
+}
+
+// Decomposition is the heavy-light layout of a static rooted forest.
+type Decomposition struct {
+	parent []int
+	depth  []int
+	head   []int // top vertex of the chain containing v
+	pos    []int // position of v in the layout
+	size   []int // number of vertices in the subtree of v
+	root   []int
+}
+
+// New decomposes the forest described by parent, where parent[v] is the
+// parent of vertex v or None if v is a root.
+func New(parent []int) (*Decomposition, error) {
+	n := len(parent)
+	children := make([][]int, n)
+	order := make([]int, 0, n)
+	for v, p := range parent {
+		switch {
+		case p == None:
+			order = append(order, v)
+		case p < 0 || p >= n:
+			return nil, ErrInvalidParent
+		default:
+			children[p] = append(children[p], v)
+		}
+	}
+
+	d := &Decomposition{
+		parent: append([]int(nil), parent...),
+		depth:  make([]int, n),
+		head:   make([]int, n),
+		pos:    make([]int, n),
+		size:   make([]int, n),
+		root:   make([]int, n),
+	}
+	roots := len(order)
+	for _, r := range order {
+		d.root[r] = r
+	}
+	for i := 0; i < len(order); i++ {
+		v := order[i]
+		for _, c := range children[v] {
+			d.depth[c] = d.depth[v] + 1
+			d.root[c] = d.root[v]
+			order = append(order, c)
+		}
+	}
+	if len(order) != n {
+		return nil, ErrCycle
+	}
+
+	// Subtree sizes bottom-up, then move each vertex's heaviest child to
+	// the front of its child list.
+	for i := n - 1; i >= 0; i-- {
+		v := order[i]
+		d.size[v]++
+		if p := parent[v]; p != None {
+			d.size[p] += d.size[v]
+		}
+	}
+	for _, cs := range children {
+		for i := 1; i < len(cs); i++ {
+			if d.size[cs[i]] > d.size[cs[0]] {
+				cs[0], cs[i] = cs[i], cs[0]
+			}
+		}
+	}
+
+	// Pre-order walk visiting the heavy child first keeps chains and
+	// subtrees contiguous.
+	next := 0
+	stack := make([]int, 0, n)
+	for i := roots - 1; i >= 0; i-- {
+		stack = append(stack, order[i])
+		d.head[order[i]] = order[i]
+	}
+	for len(stack) > 0 {
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		d.pos[v] = next
+		next++
+		cs := children[v]
+		for i := len(cs) - 1; i >= 1; i-- {
+			d.head[cs[i]] = cs[i]
+			stack = append(stack, cs[i])
+		}
+		if len(cs) > 0 {
+			d.head[cs[0]] = d.head[v]
+			stack = append(stack, cs[0])
+		}
+	}
+	return d, nil
+}
+
+// Len returns the number of vertices.
+func (d *Decomposition) Len() int {
+	return len(d.pos)
+}
+
+// Sizeof returns the approximate heap usage of the decomposition in bytes.
+func (d *Decomposition) Sizeof() int {
+	return int(unsafe.Sizeof(*d)) + intSize*(cap(d.parent)+cap(d.depth)+
+		cap(d.head)+cap(d.pos)+cap(d.size)+cap(d.root))
+}
+
+// Pos returns the position of v in the layout.
+func (d *Decomposition) Pos(v int) int {
+	return d.pos[v]
+}
+
+// Head returns the topmost vertex of the heavy chain containing v.
+func (d *Decomposition) Head(v int) int {
+	return d.head[v]
+}
+
+// Subtree returns the range of positions covered by the subtree of v.
+func (d *Decomposition) Subtree(v int) Range {
+	return Range{d.pos[v], d.pos[v] + d.size[v]}
+}
+
+// Path returns the position ranges covering the path between u and v,
+// inclusive of both endpoints. The boolean is false if u and v are in
+// different trees.
+func (d *Decomposition) Path(u, v int) ([]Range, bool) {
+	if d.root[u] != d.root[v] {
+		return nil, false
+	}
+	var ranges []Range
+	for d.head[u] != d.head[v] {
+		if d.depth[d.head[u]] < d.depth[d.head[v]] {
+			u, v = v, u
+		}
+		h := d.head[u]
+		ranges = append(ranges, Range{d.pos[h], d.pos[u] + 1})
+		u = d.parent[h]
+	}
+	if d.pos[u] > d.pos[v] {
+		u, v = v, u
+	}
+	return append(ranges, Range{d.pos[u], d.pos[v] + 1}), true
+}
+
+// LCA returns the lowest common ancestor of u and v, or None if they are in
+// different trees.
+func (d *Decomposition) LCA(u, v int) int {
+	if d.root[u] != d.root[v] {
+		return None
+	}
+	for d.head[u] != d.head[v] {
+		if d.depth[d.head[u]] < d.depth[d.head[v]] {
+			u, v = v, u
+		}
+		u = d.parent[d.head[u]]
+	}
+	if d.depth[u] < d.depth[v] {
+		return u
+	}
+	return v
+}
+
+// PathQuery maintains one value per vertex and aggregates them over tree
+// paths and subtrees. combine must be associative and commutative, such as
+// sum, min or max, and identity must be its neutral element.
+type PathQuery[T any] struct {
+	d        *Decomposition
+	tree     []T
+	n        int
+	identity T
+	combine  func(a, b T) T
+}
+
+// NewPathQuery returns a PathQuery over d with every vertex set to
+// identity.
+func NewPathQuery[T any](d *Decomposition, identity T, combine func(a, b T) T) *PathQuery[T] {
+	n := d.Len()
+	q := &PathQuery[T]{
+		d:        d,
+		tree:     make([]T, 2*n),
+		n:        n,
+		identity: identity,
+		combine:  combine,
+	}
+	for i := range q.tree {
+		q.tree[i] = identity
+	}
+	return q
+}
+
+// Decomposition returns the layout q was built on.
+func (q *PathQuery[T]) Decomposition() *Decomposition {
+	return q.d
+}
+
+// Sizeof returns the approximate heap usage of q in bytes, excluding the
+// Decomposition, which several queries may share.
+func (q *PathQuery[T]) Sizeof() int {
+	return int(unsafe.Sizeof(*q)) + cap(q.tree)*int(unsafe.Sizeof(q.identity))
+}
+
+// Get returns the value stored at vertex v.
+func (q *PathQuery[T]) Get(v int) T {
+	return q.tree[q.n+q.d.pos[v]]
+}
+
+// Set replaces the value stored at vertex v.
+func (q *PathQuery[T]) Set(v int, value T) {
+	i := q.n + q.d.pos[v]
+	q.tree[i] = value
+	for i > 1 {
+		i >>= 1
+		q.tree[i] = q.combine(q.tree[2*i], q.tree[2*i+1])
+	}
+}
+
+// Path aggregates the values on the path between u and v, inclusive of
+// both endpoints. The boolean is false if u and v are in different trees.
+func (q *PathQuery[T]) Path(u, v int) (T, bool) {
+	ranges, ok := q.d.Path(u, v)
+	if !ok {
+		return q.identity, false
+	}
+	acc := q.identity
+	for _, r := range ranges {
+		acc = q.combine(acc, q.query(r))
+	}
+	return acc, true
+}
+
+// Subtree aggregates the values in the subtree of v.
+func (q *PathQuery[T]) Subtree(v int) T {
+	return q.query(q.d.Subtree(v))
+}
+
+func (q *PathQuery[T]) query(r Range) T {
+	acc := q.identity
+	for lo, hi := r.Lo+q.n, r.Hi+q.n; lo < hi; lo, hi = lo>>1, hi>>1 {
+		if lo&1 == 1 {
+			acc = q.combine(acc, q.tree[lo])
+			lo++
+		}
+		if hi&1 == 1 {
+			hi--
+			acc = q.combine(acc, q.tree[hi])
+		}
+	}
+	return acc
+}
@@ -0,0 +1,231 @@
+package hld
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+	"unsafe"
+)
+
+func randomForest(r *rand.Rand, n int) []int {
+	perm := r.Perm(n)
+	parent := make([]int, n)
+	for i, v := range perm {
+		if i == 0 || r.Intn(20) == 0 {
+			parent[v] = None
+		} else {
+			parent[v] = perm[r.Intn(i)]
+		}
+	}
+	return parent
+}
+
+// pathVertices returns the vertices on the path between u and v, or nil if
+// they are in different trees.
+func pathVertices(parent []int, u, v int) []int {
+	onU := map[int]int{}
+	var up []int
+	for x := u; x != None; x = parent[x] {
+		onU[x] = len(up)
+		up = append(up, x)
+	}
+	var down []int
+	for x := v; x != None; x = parent[x] {
+		if i, ok := onU[x]; ok {
+			return append(up[:i+1], down...)
+		}
+		down = append(down, x)
+	}
+	return nil
+}
+
+func isDescendant(parent []int, v, a int) bool {
+	for ; v != None; v = parent[v] {
+		if v == a {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDecompositionAgainstBruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	const n = 400
+	parent := randomForest(r, n)
+	d, err := New(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for q := 0; q < 2000; q++ {
+		u, v := r.Intn(n), r.Intn(n)
+		want := pathVertices(parent, u, v)
+		ranges, ok := d.Path(u, v)
+		if ok != (want != nil) {
+			t.Fatalf("Path(%d, %d) ok = %v", u, v, ok)
+		}
+		var got []int
+		for _, rg := range ranges {
+			for p := rg.Lo; p < rg.Hi; p++ {
+				got = append(got, p)
+			}
+		}
+		var wantPos []int
+		for _, x := range want {
+			wantPos = append(wantPos, d.Pos(x))
+		}
+		slices.Sort(got)
+		slices.Sort(wantPos)
+		if !slices.Equal(got, wantPos) {
+			t.Fatalf("Path(%d, %d) covers %v, want %v", u, v, got, wantPos)
+		}
+		wantLCA := None
+		if want != nil {
+			for _, x := range want {
+				if wantLCA == None || isDescendant(parent, wantLCA, x) {
+					wantLCA = x
+				}
+			}
+		}
+		if got := d.LCA(u, v); got != wantLCA {
+			t.Fatalf("LCA(%d, %d) = %d, want %d", u, v, got, wantLCA)
+		}
+		sub := d.Subtree(u)
+		for x := 0; x < n; x++ {
+			in := sub.Lo <= d.Pos(x) && d.Pos(x) < sub.Hi
+			if in != isDescendant(parent, x, u) {
+				t.Fatalf("Subtree(%d) membership of %d = %v", u, x, in)
+			}
+		}
+	}
+}
+
+func TestPathQuerySums(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	const n = 300
+	parent := randomForest(r, n)
+	d, err := New(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := NewPathQuery(d, 0, func(a, b int) int { return a + b })
+	vals := make([]int, n)
+	for step := 0; step < 5000; step++ {
+		u, v := r.Intn(n), r.Intn(n)
+		if r.Intn(2) == 0 {
+			vals[u] = r.Intn(1000)
+			q.Set(u, vals[u])
+			continue
+		}
+		want := 0
+		p := pathVertices(parent, u, v)
+		for _, x := range p {
+			want += vals[x]
+		}
+		if got, ok := q.Path(u, v); ok != (p != nil) || got != want {
+			t.Fatalf("Path(%d, %d) = %d, %v; want %d", u, v, got, ok, want)
+		}
+		want = 0
+		for x := 0; x < n; x++ {
+			if isDescendant(parent, x, u) {
+				want += vals[x]
+			}
+		}
+		if got := q.Subtree(u); got != want {
+			t.Fatalf("Subtree(%d) = %d, want %d", u, got, want)
+		}
+	}
+}
+
+func TestLazyPathQueryAddAndMax(t *testing.T) {
+	type agg struct{ sum, max int }
+	ops := Ops[agg, int]{
+		Identity: agg{0, -1 << 62},
+		Combine: func(a, b agg) agg {
+			return agg{a.sum + b.sum, max(a.max, b.max)}
+		},
+		Apply: func(a agg, u, count int) agg {
+			return agg{a.sum + u*count, a.max + u}
+		},
+		Compose: func(first, second int) int { return first + second },
+	}
+	r := rand.New(rand.NewSource(3))
+	const n = 300
+	parent := randomForest(r, n)
+	d, err := New(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := NewLazyPathQuery(d, ops)
+	vals := make([]int, n)
+	for v := range vals {
+		q.Set(v, agg{0, 0})
+	}
+	for step := 0; step < 5000; step++ {
+		u, v := r.Intn(n), r.Intn(n)
+		p := pathVertices(parent, u, v)
+		switch r.Intn(4) {
+		case 0:
+			delta := r.Intn(100) - 50
+			if q.UpdatePath(u, v, delta) != (p != nil) {
+				t.Fatalf("UpdatePath(%d, %d) ok mismatch", u, v)
+			}
+			for _, x := range p {
+				vals[x] += delta
+			}
+		case 1:
+			delta := r.Intn(100) - 50
+			q.UpdateSubtree(u, delta)
+			for x := range vals {
+				if isDescendant(parent, x, u) {
+					vals[x] += delta
+				}
+			}
+		case 2:
+			vals[u] = r.Intn(100)
+			q.Set(u, agg{vals[u], vals[u]})
+		case 3:
+			want := ops.Identity
+			for _, x := range p {
+				want = ops.Combine(want, agg{vals[x], vals[x]})
+			}
+			if got, ok := q.Path(u, v); ok != (p != nil) || got != want {
+				t.Fatalf("Path(%d, %d) = %v, %v; want %v", u, v, got, ok, want)
+			}
+			if got := q.Get(u); got != (agg{vals[u], vals[u]}) {
+				t.Fatalf("Get(%d) = %v, want %d", u, got, vals[u])
+			}
+		}
+	}
+}
+
+func TestSizeof(t *testing.T) {
+	add := func(a, b int) int { return a + b }
+	ops := Ops[int, int]{
+		Combine: add,
+		Apply:   func(a, u, count int) int { return a + u*count },
+		Compose: func(first, second int) int { return first + second },
+	}
+	// Sizes whose int slices fill an allocation size class exactly, so the
+	// copied parent slice has no spare capacity.
+	for _, n := range []int{1, 64, 512} {
+		d, err := New(randomForest(rand.New(rand.NewSource(int64(n))), n))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Six per-vertex tables.
+		if got, want := d.Sizeof(), int(unsafe.Sizeof(*d))+6*n*intSize; got != want {
+			t.Fatalf("n=%d: Decomposition.Sizeof() = %d, want %d", n, got, want)
+		}
+		// An iterative segment tree of 2n values, excluding the shared d.
+		q := NewPathQuery(d, 0, add)
+		if got, want := q.Sizeof(), int(unsafe.Sizeof(*q))+2*n*intSize; got != want {
+			t.Fatalf("n=%d: PathQuery.Sizeof() = %d, want %d", n, got, want)
+		}
+		// A recursive segment tree of 4n nodes, each with a value, a
+		// pending update and a flag.
+		lazy := NewLazyPathQuery(d, ops)
+		if got, want := lazy.Sizeof(), int(unsafe.Sizeof(*lazy))+4*n*(2*intSize+1); got != want {
+			t.Fatalf("n=%d: LazyPathQuery.Sizeof() = %d, want %d", n, got, want)
+		}
+	}
+}
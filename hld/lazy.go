@@ -0,0 +1,176 @@
+package hld
+
+import "unsafe"
+
+// Ops describes how vertex values are aggregated and updated for a
+// LazyPathQuery. T is the value (and aggregate) type and U the update type.
+type Ops[T, U any] struct {
+	// Identity is the neutral element of Combine.
+	Identity T
+	// Combine merges two aggregates. It must be associative and
+	// commutative.
+	Combine func(a, b T) T
+	// Apply returns the result of applying u to an aggregate covering
+	// count vertices; count is 1 when u is applied to a single vertex.
+	Apply func(agg T, u U, count int) T
+	// Compose returns the update equivalent to applying first and then
+	// second.
+	Compose func(first, second U) U
+}
+
+// LazyPathQuery is a PathQuery that also supports updating every vertex on
+// a path or in a subtree, such as adding a constant along a path, using a
+// lazily propagated segment tree. Path updates and queries take
+// O(log² n), subtree updates and queries O(log n).
+type LazyPathQuery[T, U any] struct {
+	d       *Decomposition
+	ops     Ops[T, U]
+	agg     []T
+	pending []U
+	lazy    []bool
+	n       int
+}
+
+// NewLazyPathQuery returns a LazyPathQuery over d with every vertex set to
+// ops.Identity.
+func NewLazyPathQuery[T, U any](d *Decomposition, ops Ops[T, U]) *LazyPathQuery[T, U] {
+	n := d.Len()
+	q := &LazyPathQuery[T, U]{
+		d:       d,
+		ops:     ops,
+		agg:     make([]T, 4*max(n, 1)),
+		pending: make([]U, 4*max(n, 1)),
+		lazy:    make([]bool, 4*max(n, 1)),
+		n:       n,
+	}
+	for i := range q.agg {
+		q.agg[i] = ops.Identity
+	}
+	return q
+}
+
+// Decomposition returns the layout q was built on.
+func (q *LazyPathQuery[T, U]) Decomposition() *Decomposition {
+	return q.d
+}
+
+// Sizeof returns the approximate heap usage of q in bytes, excluding the
+// Decomposition, which several queries may share.
+func (q *LazyPathQuery[T, U]) Sizeof() int {
+	var u U
+	return int(unsafe.Sizeof(*q)) + cap(q.agg)*int(unsafe.Sizeof(q.ops.Identity)) +
+		cap(q.pending)*int(unsafe.Sizeof(u)) + cap(q.lazy)
+}
+
+// Get returns the value stored at vertex v.
+func (q *LazyPathQuery[T, U]) Get(v int) T {
+	p := q.d.pos[v]
+	return q.query(1, 0, q.n, Range{p, p + 1})
+}
+
+// Set replaces the value stored at vertex v.
+func (q *LazyPathQuery[T, U]) Set(v int, value T) {
+	q.set(1, 0, q.n, q.d.pos[v], value)
+}
+
+// Path aggregates the values on the path between u and v, inclusive of
+// both endpoints. The boolean is false if u and v are in different trees.
+func (q *LazyPathQuery[T, U]) Path(u, v int) (T, bool) {
+	ranges, ok := q.d.Path(u, v)
+	if !ok {
+		return q.ops.Identity, false
+	}
+	acc := q.ops.Identity
+	for _, r := range ranges {
+		acc = q.ops.Combine(acc, q.query(1, 0, q.n, r))
+	}
+	return acc, true
+}
+
+// Subtree aggregates the values in the subtree of v.
+func (q *LazyPathQuery[T, U]) Subtree(v int) T {
+	return q.query(1, 0, q.n, q.d.Subtree(v))
+}
+
+// UpdatePath applies u to every vertex on the path between a and b,
+// inclusive of both endpoints. It returns false, changing nothing, if a and
+// b are in different trees.
+func (q *LazyPathQuery[T, U]) UpdatePath(a, b int, u U) bool {
+	ranges, ok := q.d.Path(a, b)
+	if !ok {
+		return false
+	}
+	for _, r := range ranges {
+		q.update(1, 0, q.n, r, u)
+	}
+	return true
+}
+
+// UpdateSubtree applies u to every vertex in the subtree of v.
+func (q *LazyPathQuery[T, U]) UpdateSubtree(v int, u U) {
+	q.update(1, 0, q.n, q.d.Subtree(v), u)
+}
+
+// apply updates node x, which covers count positions, and records u as
+// pending for its children.
+func (q *LazyPathQuery[T, U]) apply(x, count int, u U) {
+	q.agg[x] = q.ops.Apply(q.agg[x], u, count)
+	if q.lazy[x] {
+		q.pending[x] = q.ops.Compose(q.pending[x], u)
+	} else {
+		q.pending[x], q.lazy[x] = u, true
+	}
+}
+
+func (q *LazyPathQuery[T, U]) push(x, lo, hi int) {
+	if !q.lazy[x] {
+		return
+	}
+	mid := (lo + hi) / 2
+	q.apply(2*x, mid-lo, q.pending[x])
+	q.apply(2*x+1, hi-mid, q.pending[x])
+	var zero U
+	q.pending[x], q.lazy[x] = zero, false
+}
+
+func (q *LazyPathQuery[T, U]) update(x, lo, hi int, r Range, u U) {
+	if r.Hi <= lo || hi <= r.Lo {
+		return
+	}
+	if r.Lo <= lo && hi <= r.Hi {
+		q.apply(x, hi-lo, u)
+		return
+	}
+	q.push(x, lo, hi)
+	mid := (lo + hi) / 2
+	q.update(2*x, lo, mid, r, u)
+	q.update(2*x+1, mid, hi, r, u)
+	q.agg[x] = q.ops.Combine(q.agg[2*x], q.agg[2*x+1])
+}
+
+func (q *LazyPathQuery[T, U]) set(x, lo, hi, p int, value T) {
+	if hi-lo == 1 {
+		q.agg[x] = value
+		return
+	}
+	q.push(x, lo, hi)
+	mid := (lo + hi) / 2
+	if p < mid {
+		q.set(2*x, lo, mid, p, value)
+	} else {
+		q.set(2*x+1, mid, hi, p, value)
+	}
+	q.agg[x] = q.ops.Combine(q.agg[2*x], q.agg[2*x+1])
+}
+
+func (q *LazyPathQuery[T, U]) query(x, lo, hi int, r Range) T {
+	if r.Hi <= lo || hi <= r.Lo {
+		return q.ops.Identity
+	}
+	if r.Lo <= lo && hi <= r.Hi {
+		return q.agg[x]
+	}
+	q.push(x, lo, hi)
+	mid := (lo + hi) / 2
+	return q.ops.Combine(q.query(2*x, lo, mid, r), q.query(2*x+1, mid, hi, r))
+}
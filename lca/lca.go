@@ -0,0 +1,174 @@
+// Package lca answers lowest-common-ancestor, k-th ancestor and distance
+// queries on static rooted forests using binary lifting.
+//
+// Preprocessing takes O(n log n) time and space; every query takes
+// O(log n). Forests are described by a parent slice in which roots carry
+// the parent None, which is how hierarchies such as org charts and
+// taxonomies are usually stored.
+package lca
+
+import (
+	"errors"
+	"math/bits"
+	"unsafe"
+)
+
+// None marks the parent of a root and the result of queries with no answer.
+const None = -1
+
+const intSize = int(unsafe.Sizeof(0))
+
+var (
+	// ErrInvalidParent is returned when a parent index is outside [0, n)
+	// and not None.
+	ErrInvalidParent = errors.New("lca: parent index out of range")
+	// ErrCycle is returned when the parent slice does not describe a
+	// forest.
+	ErrCycle = errors.New("lca: parent links contain a cycle")
+)
+
+// Tree is a preprocessed rooted forest.
+type Tree struct {
+	up    [][]int // up[k][v] is the 2^k-th ancestor of v, or None
+	depth []int
+	root  []int
+}
+
+// New preprocesses the forest described by parent, where parent[v] is the
+// parent of vertex v or None if v is a root.
+func New(parent []int) (*Tree, error) {
+	n := len(parent)
+	children := make([][]int, n)
+	order := make([]int, 0, n)
+	for v, p := range parent {
+		switch {
+		case p == None:
+			order = append(order, v)
+		case p < 0 || p >= n:
+			return nil, ErrInvalidParent
+		default:
+			children[p] = append(children[p], v)
+		}
+	}
+
+	t := &Tree{
+		depth: make([]int, n),
+		root:  make([]int, n),
+	}
+	for _, r := range order {
+		t.root[r] = r
+	}
+	// Breadth-first from the roots; any vertex never reached sits on a
+	// cycle.
+	for i := 0; i < len(order); i++ {
+		v := order[i]
+		for _, c := range children[v] {
+			t.depth[c] = t.depth[v] + 1
+			t.root[c] = t.root[v]
+			order = append(order, c)
+		}
+	}
+	if len(order) != n {
+		return nil, ErrCycle
+	}
+
+	levels := max(bits.Len(uint(n)), 1)
+	t.up = make([][]int, levels)
+	t.up[0] = append([]int(nil), parent...)
+	for k := 1; k < levels; k++ {
+		prev := t.up[k-1]
+		row := make([]int, n)
+		for v := range row {
+			if mid := prev[v]; mid != None {
+				row[v] = prev[mid]
+			} else {
+				row[v] = None
+			}
+		}
+		t.up[k] = row
+	}
+	return t, nil
+}
+
+// Len returns the number of vertices.
+func (t *Tree) Len() int {
+	return len(t.depth)
+}
+
+// Sizeof returns the approximate heap usage of the preprocessed forest in
+// bytes.
+func (t *Tree) Sizeof() int {
+	size := int(unsafe.Sizeof(*t)) + intSize*(cap(t.depth)+cap(t.root)) +
+		cap(t.up)*int(unsafe.Sizeof(t.depth))
+	for _, row := range t.up {
+		size += intSize * cap(row)
+	}
+	return size
+}
+
+// Depth returns the number of edges between v and its root.
+func (t *Tree) Depth(v int) int {
+	return t.depth[v]
+}
+
+// Root returns the root of the tree containing v.
+func (t *Tree) Root(v int) int {
+	return t.root[v]
+}
+
+// Parent returns the parent of v, or None if v is a root.
+func (t *Tree) Parent(v int) int {
+	return t.up[0][v]
+}
+
+// KthAncestor returns the ancestor k edges above v, or None if v is fewer
+// than k edges deep. The 0th ancestor of v is v itself.
+func (t *Tree) KthAncestor(v, k int) int {
+	if k < 0 || k > t.depth[v] {
+		return None
+	}
+	for i := 0; k > 0; i++ {
+		if k&1 == 1 {
+			v = t.up[i][v]
+		}
+		k >>= 1
+	}
+	return v
+}
+
+// LCA returns the lowest common ancestor of u and v, or None if they are in
+// different trees.
+func (t *Tree) LCA(u, v int) int {
+	if t.root[u] != t.root[v] {
+		return None
+	}
+	if t.depth[u] < t.depth[v] {
+		u, v = v, u
+	}
+	u = t.KthAncestor(u, t.depth[u]-t.depth[v])
+	if u == v {
+		return u
+	}
+	for k := len(t.up) - 1; k >= 0; k-- {
+		if a, b := t.up[k][u], t.up[k][v]; a != b {
+			u, v = a, b
+		}
+	}
+	return t.up[0][u]
+}
+
+// Distance returns the number of edges on the path between u and v, or
+// None if they are in different trees.
+func (t *Tree) Distance(u, v int) int {
+	a := t.LCA(u, v)
+	if a == None {
+		return None
+	}
+	return t.depth[u] + t.depth[v] - 2*t.depth[a]
+}
+
+// IsAncestor reports whether a is an ancestor of v. Every vertex is its own
+// ancestor.
+func (t *Tree) IsAncestor(a, v int) bool {
+	return t.depth[a] <= t.depth[v] && t.KthAncestor(v, t.depth[v]-t.depth[a]) == a
+}
@@ -0,0 +1,123 @@
+package lca
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"unsafe"
+)
+
+// randomForest returns a parent slice for a random forest on n vertices
+// with shuffled labels.
+func randomForest(r *rand.Rand, n int) []int {
+	perm := r.Perm(n)
+	parent := make([]int, n)
+	for i, v := range perm {
+		if i == 0 || r.Intn(10) == 0 {
+			parent[v] = None
+		} else {
+			parent[v] = perm[r.Intn(i)]
+		}
+	}
+	return parent
+}
+
+func ancestors(parent []int, v int) []int {
+	var a []int
+	for ; v != None; v = parent[v] {
+		a = append(a, v)
+	}
+	return a
+}
+
+func TestAgainstParentWalk(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, n := range []int{1, 2, 10, 300} {
+		parent := randomForest(r, n)
+		tr, err := New(parent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for q := 0; q < 2000; q++ {
+			u, v := r.Intn(n), r.Intn(n)
+			au, av := ancestors(parent, u), ancestors(parent, v)
+			if tr.Depth(u) != len(au)-1 || tr.Root(u) != au[len(au)-1] {
+				t.Fatalf("Depth/Root(%d) wrong", u)
+			}
+			k := r.Intn(len(au) + 1)
+			want := None
+			if k < len(au) {
+				want = au[k]
+			}
+			if got := tr.KthAncestor(u, k); got != want {
+				t.Fatalf("KthAncestor(%d, %d) = %d, want %d", u, k, got, want)
+			}
+
+			onV := map[int]bool{}
+			for _, a := range av {
+				onV[a] = true
+			}
+			lca, dist := None, None
+			for i, a := range au {
+				if onV[a] {
+					lca = a
+					dist = i + tr.Depth(v) - tr.Depth(a)
+					break
+				}
+			}
+			if got := tr.LCA(u, v); got != lca {
+				t.Fatalf("LCA(%d, %d) = %d, want %d", u, v, got, lca)
+			}
+			if got := tr.Distance(u, v); got != dist {
+				t.Fatalf("Distance(%d, %d) = %d, want %d", u, v, got, dist)
+			}
+			if got := tr.IsAncestor(v, u); got != (lca == v) {
+				t.Fatalf("IsAncestor(%d, %d) = %v", v, u, got)
+			}
+		}
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	if _, err := New([]int{1, 0}); !errors.Is(err, ErrCycle) {
+		t.Fatalf("cycle: err = %v", err)
+	}
+	if _, err := New([]int{None, 5}); !errors.Is(err, ErrInvalidParent) {
+		t.Fatalf("bad parent: err = %v", err)
+	}
+	if tr, err := New(nil); err != nil || tr.Len() != 0 {
+		t.Fatalf("empty: %v, %v", tr, err)
+	}
+}
+
+func TestDeepPath(t *testing.T) {
+	const n = 1 << 16
+	parent := make([]int, n)
+	for i := range parent {
+		parent[i] = i - 1
+	}
+	tr, err := New(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tr.LCA(n-1, n/2); got != n/2 {
+		t.Fatalf("LCA = %d, want %d", got, n/2)
+	}
+}
+
+func TestSizeof(t *testing.T) {
+	// The table has one ancestor row per bit of n. These sizes put each
+	// row on an allocation size class, so the copied parent row has no
+	// spare capacity.
+	for _, tc := range []struct{ n, levels int }{{1, 1}, {2, 2}, {128, 8}, {1024, 11}} {
+		tr, err := New(randomForest(rand.New(rand.NewSource(int64(tc.n))), tc.n))
+		if err != nil {
+			t.Fatal(err)
+		}
+		row := int(unsafe.Sizeof([]int(nil)))
+		want := int(unsafe.Sizeof(*tr)) + (2+tc.levels)*tc.n*intSize + tc.levels*row
+		if got := tr.Sizeof(); got != want {
+			t.Fatalf("n=%d: Sizeof() = %d, want %d", tc.n, got, want)
+		}
+	}
+}
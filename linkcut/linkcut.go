@@ -0,0 +1,284 @@
+// Package linkcut implements Sleator–Tarjan link-cut trees for maintaining
+// a forest under edge insertions and deletions.
+//
+// Every operation runs in O(log n) amortized time, which makes the forest
+// suitable for dynamic-connectivity workloads that a static disjoint-set
+// union cannot handle because edges may also be removed. Each vertex holds
+// a value and paths can be aggregated with a user-supplied associative
+// combine function; the function need not be commutative, path aggregates
+// are always combined in order from the first endpoint to the second.
+package linkcut
+
+import (
+	"errors"
+	"unsafe"
+)
+
+var (
+	// ErrVertexOutOfRange is returned when a vertex index is outside [0, n).
+	ErrVertexOutOfRange = errors.New("linkcut: vertex out of range")
+	// ErrAlreadyConnected is returned by Link when the two vertices are
+	// already in the same tree, since linking them would create a cycle.
+	ErrAlreadyConnected = errors.New("linkcut: vertices already connected")
+	// ErrNotConnected is returned by path queries on vertices in different
+	// trees.
+	ErrNotConnected = errors.New("linkcut: vertices not connected")
+	// ErrNoEdge is returned by Cut when the two vertices are not adjacent.
+	ErrNoEdge = errors.New("linkcut: no such edge")
+)
+
+type node[T any] struct {
+	child  [2]*node[T]
+	parent *node[T]
+	flip   bool
+	id     int
+	value  T
+	agg    T // aggregate over the splay subtree, in order
+	rev    T // aggregate over the splay subtree, reversed
+}
+
+// Forest is a collection of rooted trees over vertices 0..n-1.
+type Forest[T any] struct {
+	nodes    []node[T]
+	identity T
+	combine  func(a, b T) T
+}
+
+// New returns a forest of n isolated vertices, each holding identity.
+// combine must be associative and identity must be its neutral element.
+func New[T any](n int, identity T, combine func(a, b T) T) *Forest[T] {
+	f := &Forest[T]{
+		nodes:    make([]node[T], n),
+		identity: identity,
+		combine:  combine,
+	}
+	for i := range f.nodes {
+		x := &f.nodes[i]
+		x.id = i
+		x.value, x.agg, x.rev = identity, identity, identity
+	}
+	return f
+}
+
+// Len returns the number of vertices in the forest.
+func (f *Forest[T]) Len() int {
+	return len(f.nodes)
+}
+
+// Sizeof returns the approximate heap usage of the forest in bytes. Links
+// and cuts only rewire preallocated nodes, so it depends on Len alone.
+func (f *Forest[T]) Sizeof() int {
+	return int(unsafe.Sizeof(*f)) + cap(f.nodes)*int(unsafe.Sizeof(node[T]{}))
+}
+
+// Get returns the value held by vertex v.
+func (f *Forest[T]) Get(v int) (T, error) {
+	if !f.valid(v) {
+		return f.identity, ErrVertexOutOfRange
+	}
+	return f.nodes[v].value, nil
+}
+
+// Set replaces the value held by vertex v.
+func (f *Forest[T]) Set(v int, value T) error {
+	if !f.valid(v) {
+		return ErrVertexOutOfRange
+	}
+	x := &f.nodes[v]
+	f.splay(x)
+	x.value = value
+	f.update(x)
+	return nil
+}
+
+// Link adds the edge (u, v), joining their trees.
+func (f *Forest[T]) Link(u, v int) error {
+	if !f.valid(u) || !f.valid(v) {
+		return ErrVertexOutOfRange
+	}
+	x, y := &f.nodes[u], &f.nodes[v]
+	f.evert(x)
+	if f.root(y) == x {
+		return ErrAlreadyConnected
+	}
+	x.parent = y
+	return nil
+}
+
+// Cut removes the edge (u, v), splitting their tree in two.
+func (f *Forest[T]) Cut(u, v int) error {
+	if !f.valid(u) || !f.valid(v) {
+		return ErrVertexOutOfRange
+	}
+	x, y := &f.nodes[u], &f.nodes[v]
+	f.evert(x)
+	// root leaves x splayed at the top with the x..y path to its right, so
+	// (x, y) is an edge exactly when y is x's right child with nothing
+	// between them.
+	if f.root(y) != x || x.child[1] != y || y.child[0] != nil {
+		return ErrNoEdge
+	}
+	x.child[1] = nil
+	y.parent = nil
+	f.update(x)
+	return nil
+}
+
+// Connected reports whether u and v are in the same tree.
+func (f *Forest[T]) Connected(u, v int) (bool, error) {
+	if !f.valid(u) || !f.valid(v) {
+		return false, ErrVertexOutOfRange
+	}
+	if u == v {
+		return true, nil
+	}
+	return f.root(&f.nodes[u]) == f.root(&f.nodes[v]), nil
+}
+
+// Root returns the root of the tree containing v. Roots change whenever a
+// path query or Link re-roots the tree at one of its endpoints.
+func (f *Forest[T]) Root(v int) (int, error) {
+	if !f.valid(v) {
+		return -1, ErrVertexOutOfRange
+	}
+	return f.root(&f.nodes[v]).id, nil
+}
+
+// PathAggregate combines the values on the path from u to v, inclusive of
+// both endpoints, in path order.
+func (f *Forest[T]) PathAggregate(u, v int) (T, error) {
+	if !f.valid(u) || !f.valid(v) {
+		return f.identity, ErrVertexOutOfRange
+	}
+	x, y := &f.nodes[u], &f.nodes[v]
+	f.evert(x)
+	if f.root(y) != x {
+		return f.identity, ErrNotConnected
+	}
+	f.access(y)
+	return y.agg, nil
+}
+
+func (f *Forest[T]) valid(v int) bool {
+	return v >= 0 && v < len(f.nodes)
+}
+
+// isRoot reports whether x is the root of its splay tree (as opposed to the
+// root of the represented tree).
+func isRoot[T any](x *node[T]) bool {
+	p := x.parent
+	return p == nil || (p.child[0] != x && p.child[1] != x)
+}
+
+func (f *Forest[T]) update(x *node[T]) {
+	agg, rev := x.value, x.value
+	if l := x.child[0]; l != nil {
+		agg = f.combine(l.agg, agg)
+		rev = f.combine(rev, l.rev)
+	}
+	if r := x.child[1]; r != nil {
+		agg = f.combine(agg, r.agg)
+		rev = f.combine(r.rev, rev)
+	}
+	x.agg, x.rev = agg, rev
+}
+
+func toggle[T any](x *node[T]) {
+	if x == nil {
+		return
+	}
+	x.child[0], x.child[1] = x.child[1], x.child[0]
+	x.agg, x.rev = x.rev, x.agg
+	x.flip = !x.flip
+}
+
+func push[T any](x *node[T]) {
+	if x.flip {
+		toggle(x.child[0])
+		toggle(x.child[1])
+		x.flip = false
+	}
+}
+
+func (f *Forest[T]) rotate(x *node[T]) {
+	p := x.parent
+	g := p.parent
+	dir := 0
+	if p.child[1] == x {
+		dir = 1
+	}
+	if !isRoot(p) {
+		if g.child[0] == p {
+			g.child[0] = x
+		} else {
+			g.child[1] = x
+		}
+	}
+	x.parent = g
+
+	b := x.child[1-dir]
+	p.child[dir] = b
+	if b != nil {
+		b.parent = p
+	}
+	x.child[1-dir] = p
+	p.parent = x
+	f.update(p)
+	f.update(x)
+}
+
+func (f *Forest[T]) splay(x *node[T]) {
+	// Push pending flips top-down along the splay path first.
+	path := []*node[T]{x}
+	for y := x; !isRoot(y); y = y.parent {
+		path = append(path, y.parent)
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		push(path[i])
+	}
+
+	for !isRoot(x) {
+		p := x.parent
+		if !isRoot(p) {
+			g := p.parent
+			if (g.child[0] == p) == (p.child[0] == x) {
+				f.rotate(p)
+			} else {
+				f.rotate(x)
+			}
+		}
+		f.rotate(x)
+	}
+}
+
+// access makes the root-to-x path preferred and splays x to the top of its
+// auxiliary tree.
+func (f *Forest[T]) access(x *node[T]) {
+	var last *node[T]
+	for y := x; y != nil; y = y.parent {
+		f.splay(y)
+		y.child[1] = last
+		f.update(y)
+		last = y
+	}
+	f.splay(x)
+}
+
+// evert makes x the root of its represented tree.
+func (f *Forest[T]) evert(x *node[T]) {
+	f.access(x)
+	toggle(x)
+}
+
+func (f *Forest[T]) root(x *node[T]) *node[T] {
+	f.access(x)
+	for {
+		push(x)
+		if x.child[0] == nil {
+			break
+		}
+		x = x.child[0]
+	}
+	f.splay(x)
+	return x
+}
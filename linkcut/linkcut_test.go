@@ -0,0 +1,137 @@
+package linkcut
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+	"testing"
+	"unsafe"
+)
+
+// path returns the vertices on the path from u to v in the forest given by
+// adj, or nil if they are not connected.
+func path(adj []map[int]bool, u, v int) []int {
+	prev := make([]int, len(adj))
+	for i := range prev {
+		prev[i] = -2
+	}
+	prev[u] = -1
+	queue := []int{u}
+	for len(queue) > 0 {
+		x := queue[0]
+		queue = queue[1:]
+		for y := range adj[x] {
+			if prev[y] == -2 {
+				prev[y] = x
+				queue = append(queue, y)
+			}
+		}
+	}
+	if prev[v] == -2 {
+		return nil
+	}
+	var p []int
+	for x := v; x != -1; x = prev[x] {
+		p = append([]int{x}, p...)
+	}
+	return p
+}
+
+func TestRandomAgainstBruteForce(t *testing.T) {
+	const n = 24
+	r := rand.New(rand.NewSource(1))
+	// String concatenation is associative but not commutative, so the
+	// aggregate also checks path order.
+	f := New(n, "", func(a, b string) string { return a + b })
+	vals := make([]string, n)
+	adj := make([]map[int]bool, n)
+	for i := range adj {
+		adj[i] = map[int]bool{}
+		vals[i] = strconv.Itoa(i) + ","
+		f.Set(i, vals[i])
+	}
+	for step := 0; step < 20000; step++ {
+		u, v := r.Intn(n), r.Intn(n)
+		switch r.Intn(4) {
+		case 0:
+			err := f.Link(u, v)
+			if path(adj, u, v) != nil {
+				if !errors.Is(err, ErrAlreadyConnected) {
+					t.Fatalf("step %d: Link(%d, %d) = %v, want ErrAlreadyConnected", step, u, v, err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("step %d: Link(%d, %d) = %v", step, u, v, err)
+				}
+				adj[u][v], adj[v][u] = true, true
+			}
+		case 1:
+			err := f.Cut(u, v)
+			if adj[u][v] {
+				if err != nil {
+					t.Fatalf("step %d: Cut(%d, %d) = %v", step, u, v, err)
+				}
+				delete(adj[u], v)
+				delete(adj[v], u)
+			} else if !errors.Is(err, ErrNoEdge) {
+				t.Fatalf("step %d: Cut(%d, %d) = %v, want ErrNoEdge", step, u, v, err)
+			}
+		case 2:
+			vals[u] = strconv.Itoa(r.Intn(100)) + ","
+			f.Set(u, vals[u])
+		case 3:
+			p := path(adj, u, v)
+			got, err := f.PathAggregate(u, v)
+			if ok, _ := f.Connected(u, v); ok != (p != nil) {
+				t.Fatalf("step %d: Connected(%d, %d) = %v", step, u, v, ok)
+			}
+			if p == nil {
+				if !errors.Is(err, ErrNotConnected) {
+					t.Fatalf("step %d: PathAggregate(%d, %d) = %v, want ErrNotConnected", step, u, v, err)
+				}
+				continue
+			}
+			want := ""
+			for _, x := range p {
+				want += vals[x]
+			}
+			if err != nil || got != want {
+				t.Fatalf("step %d: PathAggregate(%d, %d) = %q, %v; want %q", step, u, v, got, err, want)
+			}
+		}
+	}
+}
+
+func TestOutOfRange(t *testing.T) {
+	f := New(3, 0, func(a, b int) int { return a + b })
+	if err := f.Link(0, 3); !errors.Is(err, ErrVertexOutOfRange) {
+		t.Fatalf("Link = %v", err)
+	}
+	if _, err := f.Get(-1); !errors.Is(err, ErrVertexOutOfRange) {
+		t.Fatalf("Get = %v", err)
+	}
+}
+
+func TestSizeof(t *testing.T) {
+	add := func(a, b int) int { return a + b }
+	header := int(unsafe.Sizeof(Forest[int]{}))
+	nodeSize := int(unsafe.Sizeof(node[int]{}))
+	for _, n := range []int{0, 1, 500} {
+		f := New(n, 0, add)
+		want := header + n*nodeSize
+		if got := f.Sizeof(); got != want {
+			t.Fatalf("n=%d: Sizeof() = %d, want %d", n, got, want)
+		}
+		// Links live inside the preallocated nodes, so restructuring the
+		// forest never changes its size.
+		for v := 1; v < n; v++ {
+			f.Link(v, v/2)
+		}
+		for v := 3; v < n; v += 3 {
+			f.Cut(v, v/2)
+		}
+		if got := f.Sizeof(); got != want {
+			t.Fatalf("n=%d: Sizeof() = %d after links and cuts, want %d", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package louds
+
+import (
+	"math/bits"
+	"sort"
+	"unsafe"
+)
+
+// wordsPerBlock is the number of 64-bit words covered by one rank sample.
+const wordsPerBlock = 8
+
+// bitVector is an immutable bit sequence with rank and select support. It
+// stores one cumulative count per 512 bits, a 12.5% space overhead.
+type bitVector struct {
+	words []uint64
+	ranks []uint64 // ranks[b]: number of ones before block b
+	n     int
+	ones  int
+}
+
+// bitBuilder accumulates bits for a bitVector.
+type bitBuilder struct {
+	words []uint64
+	n     int
+}
+
+func (b *bitBuilder) push(bit bool) {
+	if b.n%64 == 0 {
+		b.words = append(b.words, 0)
+	}
+	if bit {
+		b.words[b.n/64] |= 1 << (b.n % 64)
+	}
+	b.n++
+}
+
+func (b *bitBuilder) build() *bitVector {
+	v := &bitVector{words: b.words, n: b.n}
+	v.ranks = make([]uint64, len(v.words)/wordsPerBlock+1)
+	var total uint64
+	for i, w := range v.words {
+		if i%wordsPerBlock == 0 {
+			v.ranks[i/wordsPerBlock] = total
+		}
+		total += uint64(bits.OnesCount64(w))
+	}
+	if len(v.words)%wordsPerBlock == 0 {
+		v.ranks[len(v.words)/wordsPerBlock] = total
+	}
+	v.ones = int(total)
+	return v
+}
+
+func (v *bitVector) sizeof() int {
+	return int(unsafe.Sizeof(*v)) + 8*(cap(v.words)+cap(v.ranks))
+}
+
+func (v *bitVector) get(i int) bool {
+	return v.words[i/64]>>(i%64)&1 == 1
+}
+
+// rank1 returns the number of ones in positions [0, i).
+func (v *bitVector) rank1(i int) int {
+	w := i / 64
+	r := v.ranks[w/wordsPerBlock]
+	for k := w / wordsPerBlock * wordsPerBlock; k < w; k++ {
+		r += uint64(bits.OnesCount64(v.words[k]))
+	}
+	if off := i % 64; off != 0 {
+		r += uint64(bits.OnesCount64(v.words[w] & (1<<off - 1)))
+	}
+	return int(r)
+}
+
+// rank0 returns the number of zeros in positions [0, i).
+func (v *bitVector) rank0(i int) int {
+	return i - v.rank1(i)
+}
+
+// select1 returns the position of the k-th one, counting from 0.
+func (v *bitVector) select1(k int) int {
+	return v.selectBit(k, true)
+}
+
+// select0 returns the position of the k-th zero, counting from 0.
+func (v *bitVector) select0(k int) int {
+	return v.selectBit(k, false)
+}
+
+func (v *bitVector) selectBit(k int, one bool) int {
+	count := func(block int) int {
+		r := int(v.ranks[block])
+		if !one {
+			r = block*wordsPerBlock*64 - r
+		}
+		return r
+	}
+	// Last block whose preceding count is at most k.
+	block := sort.Search(len(v.ranks), func(b int) bool { return count(b) > k }) - 1
+	k -= count(block)
+	for w := block * wordsPerBlock; w < len(v.words); w++ {
+		word := v.words[w]
+		if !one {
+			word = ^word
+		}
+		if c := bits.OnesCount64(word); k >= c {
+			k -= c
+			continue
+		}
+		for ; k > 0; k-- {
+			word &= word - 1
+		}
+		return w*64 + bits.TrailingZeros64(word)
+	}
+	return -1
+}
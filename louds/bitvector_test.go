@@ -0,0 +1,49 @@
+package louds
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestBitVectorAgainstScan(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, n := range []int{0, 1, 63, 64, 65, 511, 512, 513, 1024, 3000} {
+		density := r.Float64()
+		var b bitBuilder
+		bitsSet := make([]bool, n)
+		for i := range bitsSet {
+			bitsSet[i] = r.Float64() < density
+			b.push(bitsSet[i])
+		}
+		v := b.build()
+		ones, zeros := 0, 0
+		for i := 0; i <= n; i++ {
+			if got := v.rank1(i); got != ones {
+				t.Fatalf("n=%d: rank1(%d) = %d, want %d", n, i, got, ones)
+			}
+			if got := v.rank0(i); got != zeros {
+				t.Fatalf("n=%d: rank0(%d) = %d, want %d", n, i, got, zeros)
+			}
+			if i == n {
+				break
+			}
+			if v.get(i) != bitsSet[i] {
+				t.Fatalf("n=%d: get(%d) = %v", n, i, !bitsSet[i])
+			}
+			if bitsSet[i] {
+				if got := v.select1(ones); got != i {
+					t.Fatalf("n=%d: select1(%d) = %d, want %d", n, ones, got, i)
+				}
+				ones++
+			} else {
+				if got := v.select0(zeros); got != i {
+					t.Fatalf("n=%d: select0(%d) = %d, want %d", n, zeros, got, i)
+				}
+				zeros++
+			}
+		}
+		if v.ones != ones {
+			t.Fatalf("n=%d: ones = %d, want %d", n, v.ones, ones)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Package louds implements a read-only succinct trie using the
+// level-order unary degree sequence (LOUDS) encoding.
+//
+// The trie shape is stored as a rank/select bit vector of about two bits
+// per node plus one label byte per edge and one terminal bit per node,
+// instead of the pointers and child arrays of a conventional trie. That
+// makes it practical to keep dictionaries of tens of millions of keys in
+// memory while still answering lookups in O(len(key) · log σ) time, where
+// σ is the alphabet size.
+package louds
+
+import (
+	"errors"
+	"sort"
+	"unsafe"
+)
+
+// ErrUnsorted is returned by Build when keys are not in ascending order.
+var ErrUnsorted = errors.New("louds: keys are not sorted")
+
+// Trie is an immutable set of strings. Each key has a dense ID in [0, Len)
+// assigned in breadth-first order, which callers can use to index a
+// parallel value slice.
+type Trie struct {
+	shape    *bitVector // LOUDS: super-root "10", then per node 1^degree 0
+	terminal *bitVector // terminal[node]: a key ends at node
+	labels   []byte     // labels[node-1]: label of the edge into node
+	size     int
+}
+
+// Build constructs a trie from keys, which must be sorted in ascending
+// order. Duplicate keys are stored once.
+func Build(keys []string) (*Trie, error) {
+	for i := 1; i < len(keys); i++ {
+		if keys[i] < keys[i-1] {
+			return nil, ErrUnsorted
+		}
+	}
+
+	// Level-order traversal where each queue entry is a node represented
+	// by the contiguous range of keys sharing its prefix.
+	type span struct{ lo, hi, depth int }
+	var shape, terminal bitBuilder
+	var labels []byte
+	shape.push(true)
+	shape.push(false)
+	size := 0
+	queue := []span{{0, len(keys), 0}}
+	for head := 0; head < len(queue); head++ {
+		s := queue[head]
+		lo := s.lo
+		isTerminal := false
+		for lo < s.hi && len(keys[lo]) == s.depth {
+			isTerminal = true
+			lo++
+		}
+		terminal.push(isTerminal)
+		if isTerminal {
+			size++
+		}
+		for lo < s.hi {
+			c := keys[lo][s.depth]
+			hi := lo + 1
+			for hi < s.hi && keys[hi][s.depth] == c {
+				hi++
+			}
+			shape.push(true)
+			labels = append(labels, c)
+			queue = append(queue, span{lo, hi, s.depth + 1})
+			lo = hi
+		}
+		shape.push(false)
+	}
+
+	return &Trie{
+		shape:    shape.build(),
+		terminal: terminal.build(),
+		labels:   labels,
+		size:     size,
+	}, nil
+}
+
+// Len returns the number of keys.
+func (t *Trie) Len() int {
+	return t.size
+}
+
+// Nodes returns the number of trie nodes, including the root.
+func (t *Trie) Nodes() int {
+	return len(t.labels) + 1
+}
+
+// children returns the half-open range of node IDs that are children of
+// node.
+func (t *Trie) children(node int) (first, end int) {
+	start := t.shape.select0(node) + 1
+	stop := t.shape.select0(node + 1)
+	first = t.shape.rank1(start)
+	return first, first + stop - start
+}
+
+// child returns the child of node reached by label c, or -1.
+func (t *Trie) child(node int, c byte) int {
+	first, end := t.children(node)
+	i := first + sort.Search(end-first, func(i int) bool { return t.labels[first+i-1] >= c })
+	if i < end && t.labels[i-1] == c {
+		return i
+	}
+	return -1
+}
+
+// walk follows key from the root and returns the node it ends at, or -1.
+func (t *Trie) walk(key string) int {
+	node := 0
+	for i := 0; i < len(key) && node >= 0; i++ {
+		node = t.child(node, key[i])
+	}
+	return node
+}
+
+// Sizeof returns the approximate heap usage of the trie in bytes, including
+// the rank directories of its bit vectors.
+func (t *Trie) Sizeof() int {
+	return int(unsafe.Sizeof(*t)) + t.shape.sizeof() + t.terminal.sizeof() + cap(t.labels)
+}
+
+// ID returns the dense ID of key. The boolean is false if key is absent.
+func (t *Trie) ID(key string) (int, bool) {
+	node := t.walk(key)
+	if node < 0 || !t.terminal.get(node) {
+		return -1, false
+	}
+	return t.terminal.rank1(node), true
+}
+
+// Contains reports whether key is in the trie.
+func (t *Trie) Contains(key string) bool {
+	_, ok := t.ID(key)
+	return ok
+}
+
+// Key returns the key with the given dense ID. It panics if id is out of
+// range.
+func (t *Trie) Key(id int) string {
+	if id < 0 || id >= t.size {
+		panic("louds: id out of range")
+	}
+	node := t.terminal.select1(id)
+	var buf []byte
+	for node > 0 {
+		buf = append(buf, t.labels[node-1])
+		// The parent owns the block of ones containing node's one.
+		node = t.shape.rank0(t.shape.select1(node)) - 1
+	}
+	for l, r := 0, len(buf)-1; l < r; l, r = l+1, r-1 {
+		buf[l], buf[r] = buf[r], buf[l]
+	}
+	return string(buf)
+}
+
+// CommonPrefixSearch calls fn for every key that is a prefix of s, shortest
+// first, until fn returns false.
+func (t *Trie) CommonPrefixSearch(s string, fn func(key string, id int) bool) {
+	node := 0
+	for i := 0; ; i++ {
+		if t.terminal.get(node) {
+			if !fn(s[:i], t.terminal.rank1(node)) {
+				return
+			}
+		}
+		if i == len(s) {
+			return
+		}
+		if node = t.child(node, s[i]); node < 0 {
+			return
+		}
+	}
+}
+
+// PrefixSearch calls fn for every key that starts with prefix, in
+// lexicographic order, until fn returns false.
+func (t *Trie) PrefixSearch(prefix string, fn func(key string, id int) bool) {
+	node := t.walk(prefix)
+	if node < 0 {
+		return
+	}
+	t.descend(node, []byte(prefix), fn)
+}
+
+func (t *Trie) descend(node int, buf []byte, fn func(string, int) bool) bool {
+	if t.terminal.get(node) {
+		if !fn(string(buf), t.terminal.rank1(node)) {
+			return false
+		}
+	}
+	first, end := t.children(node)
+	for c := first; c < end; c++ {
+		if !t.descend(c, append(buf, t.labels[c-1]), fn) {
+			return false
+		}
+	}
+	return true
+}
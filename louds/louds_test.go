@@ -0,0 +1,155 @@
+package louds
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func randomKeys(r *rand.Rand, n int) []string {
+	keys := []string{""}
+	for len(keys) < n {
+		b := make([]byte, r.Intn(8))
+		for i := range b {
+			b[i] = "abcd\x00\xff"[r.Intn(6)]
+		}
+		keys = append(keys, string(b))
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func TestTrieAgainstSortedKeys(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	keys := randomKeys(r, 2000)
+	tr, err := Build(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unique := slices.Compact(slices.Clone(keys))
+	if tr.Len() != len(unique) {
+		t.Fatalf("Len() = %d, want %d", tr.Len(), len(unique))
+	}
+	ids := make([]bool, tr.Len())
+	for _, k := range unique {
+		id, ok := tr.ID(k)
+		if !ok {
+			t.Fatalf("ID(%q) missing", k)
+		}
+		if ids[id] {
+			t.Fatalf("ID(%q) = %d is shared", k, id)
+		}
+		ids[id] = true
+		if got := tr.Key(id); got != k {
+			t.Fatalf("Key(%d) = %q, want %q", id, got, k)
+		}
+	}
+
+	for _, q := range randomKeys(r, 300) {
+		_, found := slices.BinarySearch(unique, q)
+		if tr.Contains(q) != found {
+			t.Fatalf("Contains(%q) = %v, want %v", q, !found, found)
+		}
+
+		var prefixes, wantPrefixes []string
+		tr.CommonPrefixSearch(q, func(key string, id int) bool {
+			if tr.Key(id) != key {
+				t.Fatalf("CommonPrefixSearch id %d for %q", id, key)
+			}
+			prefixes = append(prefixes, key)
+			return true
+		})
+		for i := 0; i <= len(q); i++ {
+			if _, ok := slices.BinarySearch(unique, q[:i]); ok {
+				wantPrefixes = append(wantPrefixes, q[:i])
+			}
+		}
+		if !slices.Equal(prefixes, wantPrefixes) {
+			t.Fatalf("CommonPrefixSearch(%q) = %q, want %q", q, prefixes, wantPrefixes)
+		}
+
+		p := q[:len(q)/2]
+		var got, want []string
+		tr.PrefixSearch(p, func(key string, id int) bool {
+			got = append(got, key)
+			return true
+		})
+		for _, k := range unique {
+			if strings.HasPrefix(k, p) {
+				want = append(want, k)
+			}
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("PrefixSearch(%q) = %q, want %q", p, got, want)
+		}
+	}
+}
+
+func TestBuildErrorsAndEmpty(t *testing.T) {
+	if _, err := Build([]string{"b", "a"}); !errors.Is(err, ErrUnsorted) {
+		t.Fatalf("Build(unsorted) error = %v, want ErrUnsorted", err)
+	}
+	tr, err := Build(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Len() != 0 || tr.Nodes() != 1 || tr.Contains("") || tr.Contains("a") {
+		t.Fatalf("empty trie: Len %d, Nodes %d", tr.Len(), tr.Nodes())
+	}
+}
+
+func TestEarlyStop(t *testing.T) {
+	tr, err := Build([]string{"a", "ab", "abc", "abd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	tr.PrefixSearch("a", func(string, int) bool { n++; return n < 2 })
+	tr.CommonPrefixSearch("abc", func(string, int) bool { n++; return false })
+	if n != 3 {
+		t.Fatalf("callbacks ran %d times, want 3", n)
+	}
+}
+
+func TestSizeof(t *testing.T) {
+	keys := make([]string, 3000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%016x", uint64(i)*0x9e3779b97f4a7c15)[:4+i%12]
+	}
+	slices.Sort(keys)
+	tr, err := Build(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefixes := map[string]bool{}
+	for _, k := range keys {
+		for i := range len(k) + 1 {
+			prefixes[k[:i]] = true
+		}
+	}
+	nodes := tr.Nodes()
+	if nodes != len(prefixes) {
+		t.Fatalf("Nodes() = %d, want %d distinct prefixes", nodes, len(prefixes))
+	}
+	// The shape holds 2·nodes+1 bits and the terminal flags one bit per
+	// node, each with a rank entry per block of words plus a final total;
+	// every node but the root has a label byte.
+	vector := func(bits int) (words, ranks int) {
+		words = (bits + 63) / 64
+		return words, words/wordsPerBlock + 1
+	}
+	shapeWords, shapeRanks := vector(2*nodes + 1)
+	termWords, termRanks := vector(nodes)
+	header := int(unsafe.Sizeof(*tr)) + 2*int(unsafe.Sizeof(bitVector{}))
+	lo := header + 8*(shapeWords+shapeRanks+termWords+termRanks) + nodes - 1
+	// The words and labels are appended while building, so they may carry
+	// up to as much spare capacity again.
+	hi := lo + 8*(shapeWords+termWords) + nodes + 8
+	if got := tr.Sizeof(); got < lo || got > hi {
+		t.Fatalf("Sizeof() = %d for %d nodes, want between %d and %d", got, nodes, lo, hi)
+	}
+}
@@ -0,0 +1,532 @@
+// Package mat implements fixed-size 2×2, 3×3 and 4×4 matrices and the
+// matching vectors for graphics, games and robotics code.
+//
+// All types are plain arrays, so values live on the stack, compare with ==
+// and are copied on assignment; arithmetic never allocates. Matrices are
+// stored row-major: element (i, j) of a Mat3 is m[3*i+j]. Vectors are
+// column vectors, so m.MulVec(v) computes m·v and a.Mul(b) applies b first.
+//
+// Rotation2, Scaling2, Rotation3 and Scaling3 build linear transforms.
+// Translation3 and Affine3 lift the 2D ones into homogeneous coordinates as
+// a Mat3, and Translation4 and Affine4 lift the 3D ones into a Mat4; either
+// then moves points with TransformPoint and directions with TransformDir.
+//
+// Determinants and inverses are written out in closed form rather than
+// computed by elimination. For interchange with other matrix code, each
+// type converts to and from a [][]float64 with Rows and the FromRows
+// functions.
+//
+// The structural predicates IsSymmetric, IsDiagonal, IsUpperTriangular,
+// IsLowerTriangular, IsOrthogonal and IsPositiveDefinite take an absolute
+// tolerance eps on each element they compare; eps = 0 demands exactness.
+package mat
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrShape is returned by the FromRows functions when the rows do not have
+// the expected dimensions.
+var ErrShape = errors.New("mat: wrong dimensions")
+
+// Vec2 is a two-component vector.
+type Vec2 [2]float64
+
+// Vec3 is a three-component vector.
+type Vec3 [3]float64
+
+// Vec4 is a four-component vector, typically a point (w = 1) or direction
+// (w = 0) in homogeneous coordinates.
+type Vec4 [4]float64
+
+// Dot returns the dot product of v and w.
+func (v Vec3) Dot(w Vec3) float64 {
+	return v[0]*w[0] + v[1]*w[1] + v[2]*w[2]
+}
+
+// Cross returns the cross product v × w.
+func (v Vec3) Cross(w Vec3) Vec3 {
+	return Vec3{
+		v[1]*w[2] - v[2]*w[1],
+		v[2]*w[0] - v[0]*w[2],
+		v[0]*w[1] - v[1]*w[0],
+	}
+}
+
+// Add returns v + w.
+func (v Vec3) Add(w Vec3) Vec3 {
+	return Vec3{v[0] + w[0], v[1] + w[1], v[2] + w[2]}
+}
+
+// Sub returns v - w.
+func (v Vec3) Sub(w Vec3) Vec3 {
+	return Vec3{v[0] - w[0], v[1] - w[1], v[2] - w[2]}
+}
+
+// Scale returns k·v.
+func (v Vec3) Scale(k float64) Vec3 {
+	return Vec3{k * v[0], k * v[1], k * v[2]}
+}
+
+// Len returns the Euclidean length of v.
+func (v Vec3) Len() float64 {
+	return math.Sqrt(v.Dot(v))
+}
+
+// Normalize returns v scaled to unit length, or the zero vector if v is
+// zero.
+func (v Vec3) Normalize() Vec3 {
+	l := v.Len()
+	if l == 0 {
+		return Vec3{}
+	}
+	return v.Scale(1 / l)
+}
+
+// Mat2 is a 2×2 matrix in row-major order.
+type Mat2 [4]float64
+
+// Mat3 is a 3×3 matrix in row-major order.
+type Mat3 [9]float64
+
+// Identity2 returns the 2×2 identity matrix.
+func Identity2() Mat2 {
+	return Mat2{1, 0, 0, 1}
+}
+
+// Identity3 returns the 3×3 identity matrix.
+func Identity3() Mat3 {
+	return Mat3{1, 0, 0, 0, 1, 0, 0, 0, 1}
+}
+
+// Rotation2 returns the matrix rotating by theta radians counter-clockwise.
+func Rotation2(theta float64) Mat2 {
+	s, c := math.Sincos(theta)
+	return Mat2{c, -s, s, c}
+}
+
+// Scaling2 returns the diagonal matrix scaling each axis by the
+// corresponding component of s.
+func Scaling2(s Vec2) Mat2 {
+	return Mat2{s[0], 0, 0, s[1]}
+}
+
+// Rotation3 returns the matrix rotating by theta radians about axis,
+// counter-clockwise when looking down the axis towards the origin. The
+// axis need not be unit length; a zero axis yields the identity.
+func Rotation3(axis Vec3, theta float64) Mat3 {
+	a := axis.Normalize()
+	if a == (Vec3{}) {
+		return Identity3()
+	}
+	s, c := math.Sincos(theta)
+	t := 1 - c
+	x, y, z := a[0], a[1], a[2]
+	return Mat3{
+		t*x*x + c, t*x*y - s*z, t*x*z + s*y,
+		t*x*y + s*z, t*y*y + c, t*y*z - s*x,
+		t*x*z - s*y, t*y*z + s*x, t*z*z + c,
+	}
+}
+
+// Scaling3 returns the diagonal matrix scaling each axis by the
+// corresponding component of s.
+func Scaling3(s Vec3) Mat3 {
+	return Mat3{s[0], 0, 0, 0, s[1], 0, 0, 0, s[2]}
+}
+
+// Translation3 returns the homogeneous 2D transform translating by t.
+func Translation3(t Vec2) Mat3 {
+	m := Identity3()
+	m[2], m[5] = t[0], t[1]
+	return m
+}
+
+// Affine3 returns the homogeneous 2D transform that applies m and then
+// translates by t.
+func Affine3(m Mat2, t Vec2) Mat3 {
+	return Mat3{
+		m[0], m[1], t[0],
+		m[2], m[3], t[1],
+		0, 0, 1,
+	}
+}
+
+// Mat3 returns m embedded in the upper-left corner of a homogeneous 2D
+// transform with no translation.
+func (m Mat2) Mat3() Mat3 {
+	return Affine3(m, Vec2{})
+}
+
+// Mat2 returns the upper-left 2×2 block of m, the linear part of a 2D
+// affine transform.
+func (m Mat3) Mat2() Mat2 {
+	return Mat2{m[0], m[1], m[3], m[4]}
+}
+
+// At returns element (i, j).
+func (m Mat2) At(i, j int) float64 { return m[2*i+j] }
+
+// At returns element (i, j).
+func (m Mat3) At(i, j int) float64 { return m[3*i+j] }
+
+// Add returns m + n.
+func (m Mat2) Add(n Mat2) Mat2 {
+	for i := range m {
+		m[i] += n[i]
+	}
+	return m
+}
+
+// Add returns m + n.
+func (m Mat3) Add(n Mat3) Mat3 {
+	for i := range m {
+		m[i] += n[i]
+	}
+	return m
+}
+
+// Sub returns m - n.
+func (m Mat2) Sub(n Mat2) Mat2 {
+	for i := range m {
+		m[i] -= n[i]
+	}
+	return m
+}
+
+// Sub returns m - n.
+func (m Mat3) Sub(n Mat3) Mat3 {
+	for i := range m {
+		m[i] -= n[i]
+	}
+	return m
+}
+
+// Scale returns k·m.
+func (m Mat2) Scale(k float64) Mat2 {
+	for i := range m {
+		m[i] *= k
+	}
+	return m
+}
+
+// Scale returns k·m.
+func (m Mat3) Scale(k float64) Mat3 {
+	for i := range m {
+		m[i] *= k
+	}
+	return m
+}
+
+// Mul returns the matrix product m·n.
+func (m Mat2) Mul(n Mat2) Mat2 {
+	return Mat2{
+		m[0]*n[0] + m[1]*n[2], m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2], m[2]*n[1] + m[3]*n[3],
+	}
+}
+
+// Mul returns the matrix product m·n.
+func (m Mat3) Mul(n Mat3) Mat3 {
+	var r Mat3
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			r[3*i+j] = m[3*i]*n[j] + m[3*i+1]*n[3+j] + m[3*i+2]*n[6+j]
+		}
+	}
+	return r
+}
+
+// MulVec returns m·v.
+func (m Mat2) MulVec(v Vec2) Vec2 {
+	return Vec2{m[0]*v[0] + m[1]*v[1], m[2]*v[0] + m[3]*v[1]}
+}
+
+// MulVec returns m·v.
+func (m Mat3) MulVec(v Vec3) Vec3 {
+	return Vec3{
+		m[0]*v[0] + m[1]*v[1] + m[2]*v[2],
+		m[3]*v[0] + m[4]*v[1] + m[5]*v[2],
+		m[6]*v[0] + m[7]*v[1] + m[8]*v[2],
+	}
+}
+
+// TransformPoint applies the homogeneous 2D transform m to the point p,
+// including the perspective divide when the bottom row of m is not
+// (0, 0, 1). A point mapped to w = 0 lies at infinity and is returned
+// undivided, as the direction in which it lies.
+func (m Mat3) TransformPoint(p Vec2) Vec2 {
+	r := m.MulVec(Vec3{p[0], p[1], 1})
+	if r[2] != 1 && r[2] != 0 {
+		return Vec2{r[0] / r[2], r[1] / r[2]}
+	}
+	return Vec2{r[0], r[1]}
+}
+
+// TransformDir applies the linear part of the homogeneous 2D transform m
+// to the direction d, ignoring translation.
+func (m Mat3) TransformDir(d Vec2) Vec2 {
+	return m.Mat2().MulVec(d)
+}
+
+// Transpose returns the transpose of m.
+func (m Mat2) Transpose() Mat2 {
+	return Mat2{m[0], m[2], m[1], m[3]}
+}
+
+// Transpose returns the transpose of m.
+func (m Mat3) Transpose() Mat3 {
+	return Mat3{m[0], m[3], m[6], m[1], m[4], m[7], m[2], m[5], m[8]}
+}
+
+// Det returns the determinant of m.
+func (m Mat2) Det() float64 {
+	return m[0]*m[3] - m[1]*m[2]
+}
+
+// Det returns the determinant of m.
+func (m Mat3) Det() float64 {
+	return m[0]*(m[4]*m[8]-m[5]*m[7]) -
+		m[1]*(m[3]*m[8]-m[5]*m[6]) +
+		m[2]*(m[3]*m[7]-m[4]*m[6])
+}
+
+// Inverse returns the inverse of m. The boolean is false, and the matrix
+// zero, when m is singular.
+func (m Mat2) Inverse() (Mat2, bool) {
+	det := m.Det()
+	if det == 0 {
+		return Mat2{}, false
+	}
+	return Mat2{m[3], -m[1], -m[2], m[0]}.Scale(1 / det), true
+}
+
+// Inverse returns the inverse of m. The boolean is false, and the matrix
+// zero, when m is singular.
+func (m Mat3) Inverse() (Mat3, bool) {
+	// Rows of the adjugate are the cross products of pairs of columns.
+	c0 := Vec3{m[0], m[3], m[6]}
+	c1 := Vec3{m[1], m[4], m[7]}
+	c2 := Vec3{m[2], m[5], m[8]}
+	r0, r1, r2 := c1.Cross(c2), c2.Cross(c0), c0.Cross(c1)
+	det := c0.Dot(r0)
+	if det == 0 {
+		return Mat3{}, false
+	}
+	adj := Mat3{r0[0], r0[1], r0[2], r1[0], r1[1], r1[2], r2[0], r2[1], r2[2]}
+	return adj.Scale(1 / det), true
+}
+
+// ApproxEqual reports whether every element of m is within eps of the
+// corresponding element of n.
+func (m Mat2) ApproxEqual(n Mat2, eps float64) bool {
+	return approxEqual(m[:], n[:], eps, false)
+}
+
+// ApproxEqual reports whether every element of m is within eps of the
+// corresponding element of n.
+func (m Mat3) ApproxEqual(n Mat3, eps float64) bool {
+	return approxEqual(m[:], n[:], eps, false)
+}
+
+// ApproxEqualRel reports whether every pair of corresponding elements a, b
+// of m and n satisfies |a-b| ≤ eps·max(|a|, |b|).
+func (m Mat2) ApproxEqualRel(n Mat2, eps float64) bool {
+	return approxEqual(m[:], n[:], eps, true)
+}
+
+// ApproxEqualRel reports whether every pair of corresponding elements a, b
+// of m and n satisfies |a-b| ≤ eps·max(|a|, |b|).
+func (m Mat3) ApproxEqualRel(n Mat3, eps float64) bool {
+	return approxEqual(m[:], n[:], eps, true)
+}
+
+// IsSymmetric reports whether m equals its transpose.
+func (m Mat2) IsSymmetric(eps float64) bool {
+	return symmetric(m[:], 2, eps)
+}
+
+// IsSymmetric reports whether m equals its transpose.
+func (m Mat3) IsSymmetric(eps float64) bool {
+	return symmetric(m[:], 3, eps)
+}
+
+// IsDiagonal reports whether every element off the diagonal of m is zero.
+func (m Mat2) IsDiagonal(eps float64) bool {
+	return triangular(m[:], 2, eps, true) && triangular(m[:], 2, eps, false)
+}
+
+// IsDiagonal reports whether every element off the diagonal of m is zero.
+func (m Mat3) IsDiagonal(eps float64) bool {
+	return triangular(m[:], 3, eps, true) && triangular(m[:], 3, eps, false)
+}
+
+// IsUpperTriangular reports whether every element below the diagonal of m
+// is zero.
+func (m Mat2) IsUpperTriangular(eps float64) bool {
+	return triangular(m[:], 2, eps, true)
+}
+
+// IsUpperTriangular reports whether every element below the diagonal of m
+// is zero.
+func (m Mat3) IsUpperTriangular(eps float64) bool {
+	return triangular(m[:], 3, eps, true)
+}
+
+// IsLowerTriangular reports whether every element above the diagonal of m
+// is zero.
+func (m Mat2) IsLowerTriangular(eps float64) bool {
+	return triangular(m[:], 2, eps, false)
+}
+
+// IsLowerTriangular reports whether every element above the diagonal of m
+// is zero.
+func (m Mat3) IsLowerTriangular(eps float64) bool {
+	return triangular(m[:], 3, eps, false)
+}
+
+// IsOrthogonal reports whether the rows of m are orthonormal, so that its
+// transpose is its inverse. Rotations are orthogonal with determinant 1.
+func (m Mat2) IsOrthogonal(eps float64) bool {
+	return orthogonal(m[:], 2, eps)
+}
+
+// IsOrthogonal reports whether the rows of m are orthonormal, so that its
+// transpose is its inverse. Rotations are orthogonal with determinant 1.
+func (m Mat3) IsOrthogonal(eps float64) bool {
+	return orthogonal(m[:], 3, eps)
+}
+
+// IsPositiveDefinite reports whether m is symmetric and positive definite,
+// testing the signs of its leading principal minors.
+func (m Mat2) IsPositiveDefinite(eps float64) bool {
+	return m.IsSymmetric(eps) && m[0] > 0 && m.Det() > 0
+}
+
+// IsPositiveDefinite reports whether m is symmetric and positive definite,
+// testing the signs of its leading principal minors.
+func (m Mat3) IsPositiveDefinite(eps float64) bool {
+	return m.IsSymmetric(eps) && m[0] > 0 && m[0]*m[4]-m[1]*m[3] > 0 && m.Det() > 0
+}
+
+// Rows returns m as a freshly allocated slice of rows.
+func (m Mat2) Rows() [][]float64 {
+	return rows(m[:], 2)
+}
+
+// Rows returns m as a freshly allocated slice of rows.
+func (m Mat3) Rows() [][]float64 {
+	return rows(m[:], 3)
+}
+
+// FromRows2 builds a Mat2 from two rows of two elements each.
+func FromRows2(r [][]float64) (Mat2, error) {
+	var m Mat2
+	if err := fromRows(m[:], 2, r); err != nil {
+		return Mat2{}, err
+	}
+	return m, nil
+}
+
+// FromRows3 builds a Mat3 from three rows of three elements each.
+func FromRows3(r [][]float64) (Mat3, error) {
+	var m Mat3
+	if err := fromRows(m[:], 3, r); err != nil {
+		return Mat3{}, err
+	}
+	return m, nil
+}
+
+func approxEqual(a, b []float64, eps float64, relative bool) bool {
+	for i := range a {
+		tol := eps
+		if relative {
+			tol *= max(math.Abs(a[i]), math.Abs(b[i]))
+		}
+		if !near(a[i], b[i], tol) {
+			return false
+		}
+	}
+	return true
+}
+
+// symmetric reports whether the n×n matrix m is within eps of its
+// transpose.
+func symmetric(m []float64, n int, eps float64) bool {
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			if !near(m[n*i+j], m[n*j+i], eps) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// triangular reports whether the elements of the n×n matrix m below the
+// diagonal, or above it if upper is false, are within eps of zero.
+func triangular(m []float64, n int, eps float64, upper bool) bool {
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			x := m[n*j+i]
+			if !upper {
+				x = m[n*i+j]
+			}
+			if !near(x, 0, eps) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// orthogonal reports whether the rows of the n×n matrix m are within eps
+// of orthonormal.
+func orthogonal(m []float64, n int, eps float64) bool {
+	for i := 0; i < n; i++ {
+		for k := i; k < n; k++ {
+			var dot float64
+			for j := 0; j < n; j++ {
+				dot += m[n*i+j] * m[n*k+j]
+			}
+			want := 0.0
+			if i == k {
+				want = 1
+			}
+			if !near(dot, want, eps) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// near reports whether a and b differ by at most eps. It is false if
+// either is NaN.
+func near(a, b, eps float64) bool {
+	return math.Abs(a-b) <= eps
+}
+
+func rows(m []float64, n int) [][]float64 {
+	flat := make([]float64, n*n)
+	copy(flat, m)
+	out := make([][]float64, n)
+	for i := range out {
+		out[i] = flat[n*i : n*(i+1) : n*(i+1)]
+	}
+	return out
+}
+
+func fromRows(dst []float64, n int, r [][]float64) error {
+	if len(r) != n {
+		return ErrShape
+	}
+	for i, row := range r {
+		if len(row) != n {
+			return ErrShape
+		}
+		copy(dst[n*i:], row)
+	}
+	return nil
+}
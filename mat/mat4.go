@@ -0,0 +1,237 @@
+package mat
+
+// Mat4 is a 4×4 matrix in row-major order, typically an affine transform in
+// homogeneous coordinates.
+type Mat4 [16]float64
+
+// Identity4 returns the 4×4 identity matrix.
+func Identity4() Mat4 {
+	return Mat4{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}
+}
+
+// Translation4 returns the homogeneous transform translating by t.
+func Translation4(t Vec3) Mat4 {
+	m := Identity4()
+	m[3], m[7], m[11] = t[0], t[1], t[2]
+	return m
+}
+
+// Affine4 returns the homogeneous transform that applies m and then
+// translates by t.
+func Affine4(m Mat3, t Vec3) Mat4 {
+	return Mat4{
+		m[0], m[1], m[2], t[0],
+		m[3], m[4], m[5], t[1],
+		m[6], m[7], m[8], t[2],
+		0, 0, 0, 1,
+	}
+}
+
+// Mat4 returns m embedded in the upper-left corner of a homogeneous
+// transform with no translation.
+func (m Mat3) Mat4() Mat4 {
+	return Affine4(m, Vec3{})
+}
+
+// Mat3 returns the upper-left 3×3 block of m, the linear part of an affine
+// transform.
+func (m Mat4) Mat3() Mat3 {
+	return Mat3{m[0], m[1], m[2], m[4], m[5], m[6], m[8], m[9], m[10]}
+}
+
+// At returns element (i, j).
+func (m Mat4) At(i, j int) float64 { return m[4*i+j] }
+
+// Add returns m + n.
+func (m Mat4) Add(n Mat4) Mat4 {
+	for i := range m {
+		m[i] += n[i]
+	}
+	return m
+}
+
+// Sub returns m - n.
+func (m Mat4) Sub(n Mat4) Mat4 {
+	for i := range m {
+		m[i] -= n[i]
+	}
+	return m
+}
+
+// Scale returns k·m.
+func (m Mat4) Scale(k float64) Mat4 {
+	for i := range m {
+		m[i] *= k
+	}
+	return m
+}
+
+// Mul returns the matrix product m·n.
+func (m Mat4) Mul(n Mat4) Mat4 {
+	var r Mat4
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			r[4*i+j] = m[4*i]*n[j] + m[4*i+1]*n[4+j] + m[4*i+2]*n[8+j] + m[4*i+3]*n[12+j]
+		}
+	}
+	return r
+}
+
+// MulVec returns m·v.
+func (m Mat4) MulVec(v Vec4) Vec4 {
+	var r Vec4
+	for i := 0; i < 4; i++ {
+		r[i] = m[4*i]*v[0] + m[4*i+1]*v[1] + m[4*i+2]*v[2] + m[4*i+3]*v[3]
+	}
+	return r
+}
+
+// TransformPoint applies the affine transform m to the point p, including
+// the perspective divide when the bottom row of m is not (0, 0, 0, 1). A
+// point mapped to w = 0, such as one on the camera plane of a projection,
+// has no finite image; it is returned undivided, as the direction in which
+// it lies.
+func (m Mat4) TransformPoint(p Vec3) Vec3 {
+	r := m.MulVec(Vec4{p[0], p[1], p[2], 1})
+	if r[3] != 1 && r[3] != 0 {
+		return Vec3{r[0] / r[3], r[1] / r[3], r[2] / r[3]}
+	}
+	return Vec3{r[0], r[1], r[2]}
+}
+
+// TransformDir applies the linear part of m to the direction d, ignoring
+// translation.
+func (m Mat4) TransformDir(d Vec3) Vec3 {
+	return m.Mat3().MulVec(d)
+}
+
+// Transpose returns the transpose of m.
+func (m Mat4) Transpose() Mat4 {
+	return Mat4{
+		m[0], m[4], m[8], m[12],
+		m[1], m[5], m[9], m[13],
+		m[2], m[6], m[10], m[14],
+		m[3], m[7], m[11], m[15],
+	}
+}
+
+// minors returns the 2×2 determinants of the top two rows (s) and bottom
+// two rows (c), from which both Det and Inverse are assembled.
+func (m Mat4) minors() (s, c [6]float64) {
+	s = [6]float64{
+		m[0]*m[5] - m[1]*m[4],
+		m[0]*m[6] - m[2]*m[4],
+		m[0]*m[7] - m[3]*m[4],
+		m[1]*m[6] - m[2]*m[5],
+		m[1]*m[7] - m[3]*m[5],
+		m[2]*m[7] - m[3]*m[6],
+	}
+	c = [6]float64{
+		m[8]*m[13] - m[9]*m[12],
+		m[8]*m[14] - m[10]*m[12],
+		m[8]*m[15] - m[11]*m[12],
+		m[9]*m[14] - m[10]*m[13],
+		m[9]*m[15] - m[11]*m[13],
+		m[10]*m[15] - m[11]*m[14],
+	}
+	return s, c
+}
+
+// Det returns the determinant of m.
+func (m Mat4) Det() float64 {
+	s, c := m.minors()
+	return s[0]*c[5] - s[1]*c[4] + s[2]*c[3] + s[3]*c[2] - s[4]*c[1] + s[5]*c[0]
+}
+
+// Inverse returns the inverse of m. The boolean is false, and the matrix
+// zero, when m is singular.
+func (m Mat4) Inverse() (Mat4, bool) {
+	s, c := m.minors()
+	det := s[0]*c[5] - s[1]*c[4] + s[2]*c[3] + s[3]*c[2] - s[4]*c[1] + s[5]*c[0]
+	if det == 0 {
+		return Mat4{}, false
+	}
+	adj := Mat4{
+		m[5]*c[5] - m[6]*c[4] + m[7]*c[3],
+		-m[1]*c[5] + m[2]*c[4] - m[3]*c[3],
+		m[13]*s[5] - m[14]*s[4] + m[15]*s[3],
+		-m[9]*s[5] + m[10]*s[4] - m[11]*s[3],
+
+		-m[4]*c[5] + m[6]*c[2] - m[7]*c[1],
+		m[0]*c[5] - m[2]*c[2] + m[3]*c[1],
+		-m[12]*s[5] + m[14]*s[2] - m[15]*s[1],
+		m[8]*s[5] - m[10]*s[2] + m[11]*s[1],
+
+		m[4]*c[4] - m[5]*c[2] + m[7]*c[0],
+		-m[0]*c[4] + m[1]*c[2] - m[3]*c[0],
+		m[12]*s[4] - m[13]*s[2] + m[15]*s[0],
+		-m[8]*s[4] + m[9]*s[2] - m[11]*s[0],
+
+		-m[4]*c[3] + m[5]*c[1] - m[6]*c[0],
+		m[0]*c[3] - m[1]*c[1] + m[2]*c[0],
+		-m[12]*s[3] + m[13]*s[1] - m[14]*s[0],
+		m[8]*s[3] - m[9]*s[1] + m[10]*s[0],
+	}
+	return adj.Scale(1 / det), true
+}
+
+// ApproxEqual reports whether every element of m is within eps of the
+// corresponding element of n.
+func (m Mat4) ApproxEqual(n Mat4, eps float64) bool {
+	return approxEqual(m[:], n[:], eps, false)
+}
+
+// ApproxEqualRel reports whether every pair of corresponding elements a, b
+// of m and n satisfies |a-b| ≤ eps·max(|a|, |b|).
+func (m Mat4) ApproxEqualRel(n Mat4, eps float64) bool {
+	return approxEqual(m[:], n[:], eps, true)
+}
+
+// IsSymmetric reports whether m equals its transpose.
+func (m Mat4) IsSymmetric(eps float64) bool {
+	return symmetric(m[:], 4, eps)
+}
+
+// IsDiagonal reports whether every element off the diagonal of m is zero.
+func (m Mat4) IsDiagonal(eps float64) bool {
+	return triangular(m[:], 4, eps, true) && triangular(m[:], 4, eps, false)
+}
+
+// IsUpperTriangular reports whether every element below the diagonal of m
+// is zero.
+func (m Mat4) IsUpperTriangular(eps float64) bool {
+	return triangular(m[:], 4, eps, true)
+}
+
+// IsLowerTriangular reports whether every element above the diagonal of m
+// is zero.
+func (m Mat4) IsLowerTriangular(eps float64) bool {
+	return triangular(m[:], 4, eps, false)
+}
+
+// IsOrthogonal reports whether the rows of m are orthonormal, so that its
+// transpose is its inverse.
+func (m Mat4) IsOrthogonal(eps float64) bool {
+	return orthogonal(m[:], 4, eps)
+}
+
+// IsPositiveDefinite reports whether m is symmetric and positive definite,
+// testing the signs of its leading principal minors.
+func (m Mat4) IsPositiveDefinite(eps float64) bool {
+	return m.IsSymmetric(eps) && m[0] > 0 && m[0]*m[5]-m[1]*m[4] > 0 &&
+		m.Mat3().Det() > 0 && m.Det() > 0
+}
+
+// Rows returns m as a freshly allocated slice of rows.
+func (m Mat4) Rows() [][]float64 {
+	return rows(m[:], 4)
+}
+
+// FromRows4 builds a Mat4 from four rows of four elements each.
+func FromRows4(r [][]float64) (Mat4, error) {
+	var m Mat4
+	if err := fromRows(m[:], 4, r); err != nil {
+		return Mat4{}, err
+	}
+	return m, nil
+}
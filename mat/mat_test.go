@@ -0,0 +1,689 @@
+package mat
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+const tol = 1e-9
+
+// mulN multiplies two row-major n×n matrices with the textbook triple loop.
+func mulN(a, b []float64, n int) []float64 {
+	r := make([]float64, n*n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			for k := 0; k < n; k++ {
+				r[n*i+j] += a[n*i+k] * b[n*k+j]
+			}
+		}
+	}
+	return r
+}
+
+// detN computes the determinant of a row-major n×n matrix by cofactor
+// expansion along the first row.
+func detN(a []float64, n int) float64 {
+	if n == 1 {
+		return a[0]
+	}
+	var det float64
+	sign := 1.0
+	for j := 0; j < n; j++ {
+		minor := make([]float64, 0, (n-1)*(n-1))
+		for i := 1; i < n; i++ {
+			for k := 0; k < n; k++ {
+				if k != j {
+					minor = append(minor, a[n*i+k])
+				}
+			}
+		}
+		det += sign * a[j] * detN(minor, n-1)
+		sign = -sign
+	}
+	return det
+}
+
+func isIdentity(a []float64, n int) bool {
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			want := 0.0
+			if i == j {
+				want = 1
+			}
+			if math.Abs(a[n*i+j]-want) > tol {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func approx(a, b []float64, eps float64) bool {
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > eps*max(1, math.Abs(b[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+func fill(rng *rand.Rand, m []float64) {
+	for i := range m {
+		m[i] = rng.Float64()*4 - 2
+	}
+}
+
+func TestMulAgainstBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for iter := 0; iter < 500; iter++ {
+		var a2, b2 Mat2
+		var a3, b3 Mat3
+		var a4, b4 Mat4
+		for _, m := range [][]float64{a2[:], b2[:], a3[:], b3[:], a4[:], b4[:]} {
+			fill(rng, m)
+		}
+		if got, want := a2.Mul(b2), mulN(a2[:], b2[:], 2); !approx(got[:], want, tol) {
+			t.Fatalf("%v.Mul(%v) = %v, want %v", a2, b2, got, want)
+		}
+		if got, want := a3.Mul(b3), mulN(a3[:], b3[:], 3); !approx(got[:], want, tol) {
+			t.Fatalf("%v.Mul(%v) = %v, want %v", a3, b3, got, want)
+		}
+		if got, want := a4.Mul(b4), mulN(a4[:], b4[:], 4); !approx(got[:], want, tol) {
+			t.Fatalf("%v.Mul(%v) = %v, want %v", a4, b4, got, want)
+		}
+
+		v2 := Vec2{rng.Float64(), rng.Float64()}
+		v3 := Vec3{rng.Float64(), rng.Float64(), rng.Float64()}
+		v4 := Vec4{rng.Float64(), rng.Float64(), rng.Float64(), 1}
+		got2, got3, got4 := a2.MulVec(v2), a3.MulVec(v3), a4.MulVec(v4)
+		for i := 0; i < 4; i++ {
+			var want float64
+			for j := 0; j < 4; j++ {
+				want += a4.At(i, j) * v4[j]
+			}
+			if math.Abs(got4[i]-want) > tol {
+				t.Fatalf("%v.MulVec(%v) = %v", a4, v4, got4)
+			}
+			if i < 3 && math.Abs(got3[i]-(a3.At(i, 0)*v3[0]+a3.At(i, 1)*v3[1]+a3.At(i, 2)*v3[2])) > tol {
+				t.Fatalf("%v.MulVec(%v) = %v", a3, v3, got3)
+			}
+			if i < 2 && math.Abs(got2[i]-(a2.At(i, 0)*v2[0]+a2.At(i, 1)*v2[1])) > tol {
+				t.Fatalf("%v.MulVec(%v) = %v", a2, v2, got2)
+			}
+		}
+
+		t2, t3, t4 := a2.Transpose(), a3.Transpose(), a4.Transpose()
+		for i := 0; i < 4; i++ {
+			for j := 0; j < 4; j++ {
+				if t4.At(i, j) != a4.At(j, i) ||
+					i < 3 && j < 3 && t3.At(i, j) != a3.At(j, i) ||
+					i < 2 && j < 2 && t2.At(i, j) != a2.At(j, i) {
+					t.Fatalf("Transpose of %v, %v, %v = %v, %v, %v", a2, a3, a4, t2, t3, t4)
+				}
+			}
+		}
+	}
+	id3 := Identity3()
+	if m := (Mat3{1, 2, 3, 4, 5, 6, 7, 8, 10}); m.Mul(id3) != m || id3.Mul(m) != m {
+		t.Fatal("multiplying by the identity changed the matrix")
+	}
+}
+
+func TestMat3Mat4(t *testing.T) {
+	m := Mat3{1, 2, 3, 4, 5, 6, 7, 8, 9}
+	h := m.Mat4()
+	if h.Mat3() != m {
+		t.Fatalf("%v.Mat4().Mat3() = %v", m, h.Mat3())
+	}
+	if h[3] != 0 || h[7] != 0 || h[11] != 0 || h[12] != 0 || h[13] != 0 || h[14] != 0 || h[15] != 1 {
+		t.Fatalf("%v.Mat4() = %v, want no translation or projection", m, h)
+	}
+	v := Vec3{1, -2, 3}
+	got, want := h.MulVec(Vec4{v[0], v[1], v[2], 1}), m.MulVec(v)
+	if got != (Vec4{want[0], want[1], want[2], 1}) {
+		t.Fatalf("Mat4 MulVec = %v, want %v", got, want)
+	}
+}
+
+func TestMat2Mat3(t *testing.T) {
+	m := Mat2{1, 2, 3, 4}
+	h := m.Mat3()
+	if h.Mat2() != m {
+		t.Fatalf("%v.Mat3().Mat2() = %v", m, h.Mat2())
+	}
+	if h != (Mat3{1, 2, 0, 3, 4, 0, 0, 0, 1}) {
+		t.Fatalf("%v.Mat3() = %v, want no translation or projection", m, h)
+	}
+}
+
+func TestVec3(t *testing.T) {
+	x, y, z := Vec3{1, 0, 0}, Vec3{0, 1, 0}, Vec3{0, 0, 1}
+	if x.Cross(y) != z || y.Cross(z) != x || z.Cross(x) != y {
+		t.Fatal("Cross does not follow the right-hand rule")
+	}
+	v, w := Vec3{1, 2, 3}, Vec3{-4, 5, 0.5}
+	if got := v.Dot(w); got != -4+10+1.5 {
+		t.Fatalf("Dot = %v", got)
+	}
+	if c := v.Cross(w); math.Abs(c.Dot(v)) > tol || math.Abs(c.Dot(w)) > tol {
+		t.Fatalf("%v × %v = %v, not orthogonal", v, w, c)
+	}
+	if v.Add(w).Sub(w) != v || v.Scale(2) != v.Add(v) {
+		t.Fatal("Add/Sub/Scale disagree")
+	}
+	if got := (Vec3{3, 0, 4}).Len(); got != 5 {
+		t.Fatalf("Len = %v", got)
+	}
+	if n := w.Normalize(); math.Abs(n.Len()-1) > tol {
+		t.Fatalf("Normalize(%v) = %v", w, n)
+	}
+	if (Vec3{}).Normalize() != (Vec3{}) {
+		t.Fatal("Normalize of zero is not zero")
+	}
+}
+
+func TestRotation2(t *testing.T) {
+	if got := Rotation2(math.Pi / 2).MulVec(Vec2{1, 0}); math.Abs(got[0]) > tol || math.Abs(got[1]-1) > tol {
+		t.Fatalf("quarter turn of x = %v, want y", got)
+	}
+	rng := rand.New(rand.NewSource(4))
+	for iter := 0; iter < 100; iter++ {
+		a, b := rng.Float64()*10-5, rng.Float64()*10-5
+		ra, rb := Rotation2(a), Rotation2(b)
+		if got, want := ra.Mul(rb), Rotation2(a+b); !approx(got[:], want[:], tol) {
+			t.Fatalf("R(%v)·R(%v) = %v, want %v", a, b, got, want)
+		}
+		if math.Abs(detN(ra[:], 2)-1) > tol {
+			t.Fatalf("det R(%v) = %v", a, detN(ra[:], 2))
+		}
+		if p := ra.Mul(ra.Transpose()); !isIdentity(p[:], 2) {
+			t.Fatalf("R(%v) is not orthogonal", a)
+		}
+	}
+}
+
+func TestRotation3(t *testing.T) {
+	if got := Rotation3(Vec3{0, 0, 2}, math.Pi/2).MulVec(Vec3{1, 0, 0}); !approx(got[:], []float64{0, 1, 0}, tol) {
+		t.Fatalf("quarter turn of x about z = %v, want y", got)
+	}
+	if Rotation3(Vec3{}, 1) != Identity3() {
+		t.Fatal("rotation about the zero axis is not the identity")
+	}
+	rng := rand.New(rand.NewSource(5))
+	for iter := 0; iter < 200; iter++ {
+		axis := Vec3{rng.NormFloat64(), rng.NormFloat64(), rng.NormFloat64()}
+		theta := rng.Float64()*10 - 5
+		r := Rotation3(axis, theta)
+		if det := detN(r[:], 3); math.Abs(det-1) > tol {
+			t.Fatalf("det R = %v", det)
+		}
+		if p := r.Mul(r.Transpose()); !isIdentity(p[:], 3) {
+			t.Fatalf("R(%v, %v) is not orthogonal", axis, theta)
+		}
+		if got := r.MulVec(axis); !approx(got[:], axis[:], tol) {
+			t.Fatalf("R(%v, %v) moved its axis to %v", axis, theta, got)
+		}
+		// The rotation angle is recoverable from the trace, 1 + 2cos θ.
+		if tr := r[0] + r[4] + r[8]; math.Abs(tr-(1+2*math.Cos(theta))) > tol {
+			t.Fatalf("trace of R(%v, %v) = %v", axis, theta, tr)
+		}
+		phi := rng.Float64()*10 - 5
+		if got, want := r.Mul(Rotation3(axis, phi)), Rotation3(axis, theta+phi); !approx(got[:], want[:], tol) {
+			t.Fatalf("rotations about %v do not compose", axis)
+		}
+	}
+	z := Rotation3(Vec3{0, 0, 1}, 0.7)
+	r2 := Rotation2(0.7)
+	if want := (Mat3{r2[0], r2[1], 0, r2[2], r2[3], 0, 0, 0, 1}); !approx(z[:], want[:], tol) {
+		t.Fatalf("rotation about z = %v, want %v", z, want)
+	}
+}
+
+func TestScaling3(t *testing.T) {
+	s := Scaling3(Vec3{2, -3, 0.5})
+	if got := s.MulVec(Vec3{1, 1, 1}); got != (Vec3{2, -3, 0.5}) {
+		t.Fatalf("Scaling3 MulVec = %v", got)
+	}
+	if det := detN(s[:], 3); det != -3 {
+		t.Fatalf("det Scaling3 = %v", det)
+	}
+}
+
+func TestScaling2(t *testing.T) {
+	s := Scaling2(Vec2{-2, 0.5})
+	if got := s.MulVec(Vec2{1, 1}); got != (Vec2{-2, 0.5}) {
+		t.Fatalf("Scaling2 MulVec = %v", got)
+	}
+	if det := detN(s[:], 2); det != -1 {
+		t.Fatalf("det Scaling2 = %v", det)
+	}
+}
+
+func TestAffine4(t *testing.T) {
+	tr := Vec3{1, 2, 3}
+	if got := Translation4(tr).TransformPoint(Vec3{1, 1, 1}); got != (Vec3{2, 3, 4}) {
+		t.Fatalf("translated point = %v", got)
+	}
+	if got := Translation4(tr).TransformDir(Vec3{1, 1, 1}); got != (Vec3{1, 1, 1}) {
+		t.Fatalf("translated direction = %v", got)
+	}
+
+	rng := rand.New(rand.NewSource(6))
+	for iter := 0; iter < 100; iter++ {
+		var lin Mat3
+		fill(rng, lin[:])
+		off := Vec3{rng.Float64(), rng.Float64(), rng.Float64()}
+		m := Affine4(lin, off)
+		if got, want := m, Translation4(off).Mul(lin.Mat4()); !approx(got[:], want[:], tol) {
+			t.Fatalf("Affine4 = %v, want T·L = %v", got, want)
+		}
+		p := Vec3{rng.Float64(), rng.Float64(), rng.Float64()}
+		if got, want := m.TransformPoint(p), lin.MulVec(p).Add(off); !approx(got[:], want[:], tol) {
+			t.Fatalf("TransformPoint(%v) = %v, want %v", p, got, want)
+		}
+		if got, want := m.TransformDir(p), lin.MulVec(p); !approx(got[:], want[:], tol) {
+			t.Fatalf("TransformDir(%v) = %v, want %v", p, got, want)
+		}
+	}
+}
+
+func TestAffine3(t *testing.T) {
+	tr := Vec2{1, 2}
+	if got := Translation3(tr).TransformPoint(Vec2{1, 1}); got != (Vec2{2, 3}) {
+		t.Fatalf("translated point = %v", got)
+	}
+	if got := Translation3(tr).TransformDir(Vec2{1, 1}); got != (Vec2{1, 1}) {
+		t.Fatalf("translated direction = %v", got)
+	}
+
+	rng := rand.New(rand.NewSource(7))
+	for iter := 0; iter < 100; iter++ {
+		scale := Scaling2(Vec2{rng.Float64() + 0.5, rng.Float64() + 0.5})
+		lin := Rotation2(rng.Float64() * 2 * math.Pi).Mul(scale)
+		off := Vec2{rng.Float64(), rng.Float64()}
+		m := Affine3(lin, off)
+		if got, want := m, Translation3(off).Mul(lin.Mat3()); !approx(got[:], want[:], tol) {
+			t.Fatalf("Affine3 = %v, want T·L = %v", got, want)
+		}
+		p := Vec2{rng.Float64(), rng.Float64()}
+		lp := lin.MulVec(p)
+		if got, want := m.TransformPoint(p), (Vec2{lp[0] + off[0], lp[1] + off[1]}); !approx(got[:], want[:], tol) {
+			t.Fatalf("TransformPoint(%v) = %v, want %v", p, got, want)
+		}
+		if got := m.TransformDir(p); !approx(got[:], lp[:], tol) {
+			t.Fatalf("TransformDir(%v) = %v, want %v", p, got, lp)
+		}
+		inv, ok := m.Inverse()
+		if back := inv.TransformPoint(m.TransformPoint(p)); !ok || !approx(back[:], p[:], tol) {
+			t.Fatalf("inverse maps %v back to %v", p, back)
+		}
+	}
+}
+
+func TestTransformPointPerspective(t *testing.T) {
+	m := Identity4()
+	m[14], m[15] = 1, 0 // w = z
+	if got := m.TransformPoint(Vec3{2, 4, 2}); got != (Vec3{1, 2, 1}) {
+		t.Fatalf("perspective divide = %v", got)
+	}
+	if got := m.TransformPoint(Vec3{2, 4, 0}); got != (Vec3{2, 4, 0}) {
+		t.Fatalf("w = 0 = %v, want undivided", got)
+	}
+
+	m2 := Identity3()
+	m2[7], m2[8] = 1, 0 // w = y
+	if got := m2.TransformPoint(Vec2{3, 2}); got != (Vec2{1.5, 1}) {
+		t.Fatalf("2D perspective divide = %v", got)
+	}
+	if got := m2.TransformPoint(Vec2{3, 0}); got != (Vec2{3, 0}) {
+		t.Fatalf("2D w = 0 = %v, want undivided", got)
+	}
+}
+
+func TestDetInverseAgainstBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	for iter := 0; iter < 500; iter++ {
+		var a2 Mat2
+		var a3 Mat3
+		var a4 Mat4
+		fill(rng, a2[:])
+		fill(rng, a3[:])
+		fill(rng, a4[:])
+		dets := []float64{a2.Det(), a3.Det(), a4.Det()}
+		for k, m := range [][]float64{a2[:], a3[:], a4[:]} {
+			if want := detN(m, k+2); math.Abs(dets[k]-want) > tol {
+				t.Fatalf("Det of %v = %v, want %v", m, dets[k], want)
+			}
+		}
+
+		inv2, ok2 := a2.Inverse()
+		inv3, ok3 := a3.Inverse()
+		inv4, ok4 := a4.Inverse()
+		if !ok2 || !ok3 || !ok4 {
+			t.Fatalf("random matrices reported singular: %v %v %v", ok2, ok3, ok4)
+		}
+		// Nearly singular matrices amplify rounding, so only check the
+		// product for well-conditioned ones.
+		p2, p3, p4 := a2.Mul(inv2), a3.Mul(inv3), a4.Mul(inv4)
+		for k, p := range [][]float64{p2[:], p3[:], p4[:]} {
+			if math.Abs(dets[k]) > 1e-6 && !isIdentity(p, k+2) {
+				t.Fatalf("product with inverse = %v, want identity", p)
+			}
+		}
+	}
+}
+
+func TestAffineInverse(t *testing.T) {
+	rng := rand.New(rand.NewSource(8))
+	for iter := 0; iter < 100; iter++ {
+		lin := Rotation3(Vec3{rng.NormFloat64(), rng.NormFloat64(), rng.NormFloat64()}, rng.Float64()*6).
+			Mul(Scaling3(Vec3{1 + rng.Float64(), 1 + rng.Float64(), 1 + rng.Float64()}))
+		m := Affine4(lin, Vec3{rng.Float64(), rng.Float64(), rng.Float64()})
+		inv, ok := m.Inverse()
+		if !ok {
+			t.Fatal("affine transform reported singular")
+		}
+		p := Vec3{rng.Float64(), rng.Float64(), rng.Float64()}
+		if got := inv.TransformPoint(m.TransformPoint(p)); !approx(got[:], p[:], 1e-9) {
+			t.Fatalf("inverse transform of %v = %v", p, got)
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	rng := rand.New(rand.NewSource(9))
+	for iter := 0; iter < 100; iter++ {
+		var a2, b2 Mat2
+		var a3, b3 Mat3
+		var a4, b4 Mat4
+		for _, m := range [][]float64{a2[:], b2[:], a3[:], b3[:], a4[:], b4[:]} {
+			fill(rng, m)
+		}
+		k := rng.Float64()
+		sum2, diff2, scaled2 := a2.Add(b2), a2.Sub(b2), a2.Scale(k)
+		sum3, diff3, scaled3 := a3.Add(b3), a3.Sub(b3), a3.Scale(k)
+		sum4, diff4, scaled4 := a4.Add(b4), a4.Sub(b4), a4.Scale(k)
+		for i := range a4 {
+			if sum4[i] != a4[i]+b4[i] || diff4[i] != a4[i]-b4[i] || scaled4[i] != k*a4[i] ||
+				i < 9 && (sum3[i] != a3[i]+b3[i] || diff3[i] != a3[i]-b3[i] || scaled3[i] != k*a3[i]) ||
+				i < 4 && (sum2[i] != a2[i]+b2[i] || diff2[i] != a2[i]-b2[i] || scaled2[i] != k*a2[i]) {
+				t.Fatalf("element %d: Add/Sub/Scale disagree with elementwise arithmetic", i)
+			}
+		}
+	}
+}
+
+func TestSingular(t *testing.T) {
+	if m, ok := (Mat2{1, 2, 2, 4}).Inverse(); ok || m != (Mat2{}) {
+		t.Errorf("Mat2 Inverse of singular = %v, %v", m, ok)
+	}
+	if m, ok := (Mat3{1, 2, 3, 4, 5, 6, 7, 8, 9}).Inverse(); ok || m != (Mat3{}) {
+		t.Errorf("Mat3 Inverse of singular = %v, %v", m, ok)
+	}
+	var m4 Mat4
+	m4[0], m4[5], m4[10] = 1, 1, 1
+	if m, ok := m4.Inverse(); ok || m != (Mat4{}) {
+		t.Errorf("Mat4 Inverse of singular = %v, %v", m, ok)
+	}
+}
+
+func TestIdentityDet(t *testing.T) {
+	if Identity2().Det() != 1 || Identity3().Det() != 1 || Identity4().Det() != 1 {
+		t.Fatal("identity determinant is not 1")
+	}
+	if inv, ok := Identity4().Inverse(); !ok || inv != Identity4() {
+		t.Fatalf("inverse of identity = %v, %v", inv, ok)
+	}
+}
+
+func TestRows(t *testing.T) {
+	m3 := Mat3{1, 2, 3, 4, 5, 6, 7, 8, 9}
+	r := m3.Rows()
+	if len(r) != 3 || r[1][2] != 6 {
+		t.Fatalf("Rows() = %v", r)
+	}
+	r[0] = append(r[0], 42) // must not clobber row 1
+	if r[1][0] != 4 {
+		t.Fatal("appending to a row overwrote the next row")
+	}
+	if got, err := FromRows3(m3.Rows()); err != nil || got != m3 {
+		t.Fatalf("FromRows3(Rows()) = %v, %v", got, err)
+	}
+	m2 := Mat2{1, 2, 3, 4}
+	if got, err := FromRows2(m2.Rows()); err != nil || got != m2 {
+		t.Fatalf("FromRows2(Rows()) = %v, %v", got, err)
+	}
+	m4 := Identity4()
+	m4[3] = 5
+	if got, err := FromRows4(m4.Rows()); err != nil || got != m4 {
+		t.Fatalf("FromRows4(Rows()) = %v, %v", got, err)
+	}
+
+	bad := [][][]float64{
+		nil,
+		{{1, 2, 3}, {4, 5, 6}},
+		{{1, 2, 3}, {4, 5}, {7, 8, 9}},
+		{{1, 2, 3, 4}, {4, 5, 6}, {7, 8, 9}},
+	}
+	for _, r := range bad {
+		if _, err := FromRows3(r); !errors.Is(err, ErrShape) {
+			t.Errorf("FromRows3(%v) error = %v, want ErrShape", r, err)
+		}
+	}
+	if _, err := FromRows2(m3.Rows()); !errors.Is(err, ErrShape) {
+		t.Errorf("FromRows2 of 3×3 error = %v, want ErrShape", err)
+	}
+	if _, err := FromRows4(m3.Rows()); !errors.Is(err, ErrShape) {
+		t.Errorf("FromRows4 of 3×3 error = %v, want ErrShape", err)
+	}
+}
+
+type structured interface {
+	IsSymmetric(eps float64) bool
+	IsDiagonal(eps float64) bool
+	IsUpperTriangular(eps float64) bool
+	IsLowerTriangular(eps float64) bool
+	IsOrthogonal(eps float64) bool
+	IsPositiveDefinite(eps float64) bool
+}
+
+func asStructured(m []float64) structured {
+	switch len(m) {
+	case 4:
+		return Mat2(m)
+	case 9:
+		return Mat3(m)
+	default:
+		return Mat4(m)
+	}
+}
+
+// choleskyOK reports whether the symmetric n×n matrix m has a Cholesky
+// factorization, which is the case exactly when it is positive definite.
+func choleskyOK(m []float64, n int) bool {
+	l := make([]float64, n*n)
+	for j := 0; j < n; j++ {
+		d := m[n*j+j]
+		for k := 0; k < j; k++ {
+			d -= l[n*j+k] * l[n*j+k]
+		}
+		if d <= 0 {
+			return false
+		}
+		l[n*j+j] = math.Sqrt(d)
+		for i := j + 1; i < n; i++ {
+			x := m[n*i+j]
+			for k := 0; k < j; k++ {
+				x -= l[n*i+k] * l[n*j+k]
+			}
+			l[n*i+j] = x / l[n*j+j]
+		}
+	}
+	return true
+}
+
+func transposeN(m []float64, n int) []float64 {
+	t := make([]float64, n*n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			t[n*j+i] = m[n*i+j]
+		}
+	}
+	return t
+}
+
+func TestStructuralPredicates(t *testing.T) {
+	rng := rand.New(rand.NewSource(10))
+	for n := 2; n <= 4; n++ {
+		for iter := 0; iter < 200; iter++ {
+			a := make([]float64, n*n)
+			fill(rng, a)
+			at := transposeN(a, n)
+			i, j := rng.Intn(n), rng.Intn(n-1)
+			if j >= i {
+				j++
+			}
+
+			sym := make([]float64, n*n)
+			for k := range sym {
+				sym[k] = a[k] + at[k]
+			}
+			if s := asStructured(sym); !s.IsSymmetric(0) {
+				t.Fatalf("A+Aᵀ = %v not symmetric", sym)
+			}
+			sym[n*i+j] += 1e-3
+			if s := asStructured(sym); s.IsSymmetric(1e-6) || !s.IsSymmetric(1e-2) {
+				t.Fatalf("%v: perturbed symmetry ignores eps", sym)
+			}
+
+			upper := append([]float64(nil), a...)
+			for r := 0; r < n; r++ {
+				for c := 0; c < r; c++ {
+					upper[n*r+c] = 0
+				}
+			}
+			u, l := asStructured(upper), asStructured(transposeN(upper, n))
+			if !u.IsUpperTriangular(0) || u.IsLowerTriangular(0) || u.IsDiagonal(0) {
+				t.Fatalf("%v misclassified as upper triangular", upper)
+			}
+			if !l.IsLowerTriangular(0) || l.IsUpperTriangular(0) || l.IsDiagonal(0) {
+				t.Fatalf("%v misclassified as lower triangular", transposeN(upper, n))
+			}
+
+			diag := make([]float64, n*n)
+			for r := 0; r < n; r++ {
+				diag[n*r+r] = a[n*r+r]
+			}
+			if d := asStructured(diag); !d.IsDiagonal(0) || !d.IsUpperTriangular(0) || !d.IsLowerTriangular(0) || !d.IsSymmetric(0) {
+				t.Fatalf("%v not recognized as diagonal", diag)
+			}
+			diag[n*i+j] = 1e-9
+			if d := asStructured(diag); d.IsDiagonal(0) || !d.IsDiagonal(1e-8) {
+				t.Fatalf("%v: diagonal check ignores eps", diag)
+			}
+
+			// Cholesky decides definiteness for symmetric matrices; AᵀA+I
+			// is always positive definite.
+			if got, want := asStructured(sym).IsPositiveDefinite(1e-2), choleskyOK(sym, n); got != want {
+				t.Fatalf("IsPositiveDefinite(%v) = %v, want %v", sym, got, want)
+			}
+			gram := mulN(at, a, n)
+			for r := 0; r < n; r++ {
+				gram[n*r+r]++
+			}
+			if !asStructured(gram).IsPositiveDefinite(1e-12) {
+				t.Fatalf("AᵀA+I = %v not positive definite", gram)
+			}
+			if asStructured(a).IsPositiveDefinite(0) && !asStructured(a).IsSymmetric(0) {
+				t.Fatalf("non-symmetric %v reported positive definite", a)
+			}
+		}
+	}
+}
+
+func TestIsOrthogonal(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	for iter := 0; iter < 100; iter++ {
+		axis := Vec3{rng.NormFloat64(), rng.NormFloat64(), rng.NormFloat64()}
+		theta := rng.Float64() * 10
+		r2, r3 := Rotation2(theta), Rotation3(axis, theta)
+		r4 := r3.Mat4()
+		if !r2.IsOrthogonal(tol) || !r3.IsOrthogonal(tol) || !r4.IsOrthogonal(tol) {
+			t.Fatalf("rotation by %v about %v not orthogonal", theta, axis)
+		}
+		if r2.Scale(1.01).IsOrthogonal(1e-3) || r3.Mul(Scaling3(Vec3{1, 1, 1.01})).IsOrthogonal(1e-3) {
+			t.Fatal("scaled rotation reported orthogonal")
+		}
+		if Affine4(r3, Vec3{1, 0, 0}).IsOrthogonal(1e-3) {
+			t.Fatal("translation reported orthogonal")
+		}
+	}
+	// A reflection is orthogonal too, with determinant -1.
+	if m := (Mat3{0, 1, 0, 1, 0, 0, 0, 0, 1}); !m.IsOrthogonal(0) || m.Det() != -1 {
+		t.Fatal("permutation matrix not orthogonal")
+	}
+}
+
+func TestPredicatesNaN(t *testing.T) {
+	m := Identity3()
+	m[1] = math.NaN()
+	inf := math.Inf(1)
+	if m.IsSymmetric(inf) || m.IsDiagonal(inf) || m.IsLowerTriangular(inf) ||
+		m.IsOrthogonal(inf) || m.IsPositiveDefinite(inf) {
+		t.Fatal("matrix containing NaN satisfied a predicate")
+	}
+	if !m.IsUpperTriangular(0) {
+		t.Fatal("NaN above the diagonal affected IsUpperTriangular")
+	}
+}
+
+func TestApproxEqual(t *testing.T) {
+	a := Rotation3(Vec3{1, 2, 3}, 0.4)
+	// Rotating by the same angle in many small steps rounds differently.
+	b := Identity3()
+	for i := 0; i < 40; i++ {
+		b = Rotation3(Vec3{1, 2, 3}, 0.01).Mul(b)
+	}
+	if a == b {
+		t.Fatal("test needs results that differ in rounding")
+	}
+	if !a.ApproxEqual(b, 1e-12) || !a.ApproxEqualRel(b, 1e-9) {
+		t.Errorf("%v and %v not approximately equal", a, b)
+	}
+	if a.ApproxEqual(b, 0) || a.ApproxEqualRel(b, 0) {
+		t.Errorf("%v and %v equal with zero tolerance", a, b)
+	}
+	if !a.ApproxEqual(a, 0) || !a.ApproxEqualRel(a, 0) {
+		t.Error("a matrix is not equal to itself with zero tolerance")
+	}
+
+	// Absolute tolerance ignores magnitude, relative tolerance scales with it.
+	big, bigger := Mat2{1e9, 0, 0, 1}, Mat2{1e9 + 1, 0, 0, 1}
+	if big.ApproxEqual(bigger, 1e-3) || !big.ApproxEqualRel(bigger, 1e-6) {
+		t.Errorf("%v vs %v: absolute %v, relative %v", big, bigger,
+			big.ApproxEqual(bigger, 1e-3), big.ApproxEqualRel(bigger, 1e-6))
+	}
+	small, smaller := Mat2{1e-9, 0, 0, 1}, Mat2{2e-9, 0, 0, 1}
+	if !small.ApproxEqual(smaller, 1e-6) || small.ApproxEqualRel(smaller, 0.1) {
+		t.Errorf("%v vs %v: absolute %v, relative %v", small, smaller,
+			small.ApproxEqual(smaller, 1e-6), small.ApproxEqualRel(smaller, 0.1))
+	}
+
+	m4 := Identity4()
+	n4 := m4
+	n4[7] = 1e-10
+	if !m4.ApproxEqual(n4, 1e-9) || m4.ApproxEqual(n4, 1e-11) {
+		t.Error("Mat4 ApproxEqual ignores its tolerance")
+	}
+	if m4.ApproxEqualRel(n4, 0.5) {
+		t.Error("Mat4 ApproxEqualRel equates zero with a non-zero element")
+	}
+
+	nan := Identity4()
+	nan[0] = math.NaN()
+	if nan.ApproxEqual(nan, math.Inf(1)) || nan.ApproxEqualRel(nan, math.Inf(1)) {
+		t.Error("matrices containing NaN compared equal")
+	}
+}
@@ -0,0 +1,190 @@
+// Package quat implements quaternions for representing 3D rotations.
+//
+// Unit quaternions compose rotations with fewer operations than 3×3
+// matrices, interpolate smoothly with Slerp and are cheap to renormalize,
+// so chains of incremental rotations do not drift away from a rotation the
+// way repeated matrix products do. Conversions to and from mat.Mat3 and
+// mat.Mat4 connect them to the rest of a transform pipeline.
+package quat
+
+import (
+	"math"
+
+	"github.com/anon-org/ds/mat"
+)
+
+// Quat is the quaternion W + Xi + Yj + Zk. Rotations are represented by
+// unit quaternions; q and -q describe the same rotation.
+type Quat struct {
+	W, X, Y, Z float64
+}
+
+// Identity returns the quaternion of the identity rotation.
+func Identity() Quat {
+	return Quat{W: 1}
+}
+
+// FromAxisAngle returns the rotation by theta radians about axis, with the
+// same orientation convention as mat.Rotation3. A zero axis yields the
+// identity.
+func FromAxisAngle(axis mat.Vec3, theta float64) Quat {
+	a := axis.Normalize()
+	if a == (mat.Vec3{}) {
+		return Identity()
+	}
+	s, c := math.Sincos(theta / 2)
+	return Quat{c, s * a[0], s * a[1], s * a[2]}
+}
+
+// AxisAngle returns the axis and angle, in [0, 2π), of the rotation q,
+// which must be a unit quaternion. The identity yields the x axis and a
+// zero angle.
+func (q Quat) AxisAngle() (mat.Vec3, float64) {
+	v := mat.Vec3{q.X, q.Y, q.Z}
+	s := v.Len()
+	if s == 0 {
+		return mat.Vec3{1, 0, 0}, 0
+	}
+	return v.Scale(1 / s), 2 * math.Atan2(s, q.W)
+}
+
+// Mul returns the Hamilton product q·r, the rotation that applies r first
+// and then q.
+func (q Quat) Mul(r Quat) Quat {
+	return Quat{
+		q.W*r.W - q.X*r.X - q.Y*r.Y - q.Z*r.Z,
+		q.W*r.X + q.X*r.W + q.Y*r.Z - q.Z*r.Y,
+		q.W*r.Y - q.X*r.Z + q.Y*r.W + q.Z*r.X,
+		q.W*r.Z + q.X*r.Y - q.Y*r.X + q.Z*r.W,
+	}
+}
+
+// Conj returns the conjugate of q, which for a unit quaternion is the
+// inverse rotation.
+func (q Quat) Conj() Quat {
+	return Quat{q.W, -q.X, -q.Y, -q.Z}
+}
+
+// Inverse returns the multiplicative inverse of q. The boolean is false
+// when q is zero.
+func (q Quat) Inverse() (Quat, bool) {
+	n := q.Dot(q)
+	if n == 0 {
+		return Quat{}, false
+	}
+	c := q.Conj()
+	return Quat{c.W / n, c.X / n, c.Y / n, c.Z / n}, true
+}
+
+// Dot returns the four-dimensional dot product of q and r.
+func (q Quat) Dot(r Quat) float64 {
+	return q.W*r.W + q.X*r.X + q.Y*r.Y + q.Z*r.Z
+}
+
+// Norm returns the length of q.
+func (q Quat) Norm() float64 {
+	return math.Sqrt(q.Dot(q))
+}
+
+// Normalize returns q scaled to unit length, or the identity if q is zero.
+// Renormalizing after a series of products keeps rounding errors from
+// accumulating.
+func (q Quat) Normalize() Quat {
+	n := q.Norm()
+	if n == 0 {
+		return Identity()
+	}
+	return Quat{q.W / n, q.X / n, q.Y / n, q.Z / n}
+}
+
+// Rotate applies the rotation q, which must be a unit quaternion, to v.
+func (q Quat) Rotate(v mat.Vec3) mat.Vec3 {
+	// v' = v + 2w(u × v) + 2u × (u × v), with u the vector part of q.
+	u := mat.Vec3{q.X, q.Y, q.Z}
+	t := u.Cross(v).Scale(2)
+	return v.Add(t.Scale(q.W)).Add(u.Cross(t))
+}
+
+// Slerp interpolates along the shortest arc between the unit quaternions
+// a and b, returning a at t = 0 and b, or -b, at t = 1.
+func Slerp(a, b Quat, t float64) Quat {
+	d := a.Dot(b)
+	if d < 0 {
+		b, d = Quat{-b.W, -b.X, -b.Y, -b.Z}, -d
+	}
+	var wa, wb float64
+	if d > 0.9995 {
+		// Nearly parallel: sin θ underflows, so fall back to normalized
+		// linear interpolation.
+		wa, wb = 1-t, t
+	} else {
+		theta := math.Acos(d)
+		sin := math.Sin(theta)
+		wa, wb = math.Sin((1-t)*theta)/sin, math.Sin(t*theta)/sin
+	}
+	return Quat{
+		wa*a.W + wb*b.W,
+		wa*a.X + wb*b.X,
+		wa*a.Y + wb*b.Y,
+		wa*a.Z + wb*b.Z,
+	}.Normalize()
+}
+
+// Mat3 returns the rotation matrix of the unit quaternion q.
+func (q Quat) Mat3() mat.Mat3 {
+	w, x, y, z := q.W, q.X, q.Y, q.Z
+	return mat.Mat3{
+		1 - 2*(y*y+z*z), 2 * (x*y - w*z), 2 * (x*z + w*y),
+		2 * (x*y + w*z), 1 - 2*(x*x+z*z), 2 * (y*z - w*x),
+		2 * (x*z - w*y), 2 * (y*z + w*x), 1 - 2*(x*x+y*y),
+	}
+}
+
+// Mat4 returns the homogeneous transform of the unit quaternion q.
+func (q Quat) Mat4() mat.Mat4 {
+	return q.Mat3().Mat4()
+}
+
+// FromMat3 returns the unit quaternion of the rotation in m, with a
+// non-negative W. m must be a rotation whose columns may carry positive
+// per-axis scale, R·Scaling3(s) with every s[i] > 0; the columns are
+// normalized before the rotation is read off. Any other matrix, one with
+// shear or a reflection for instance, has no such rotation, and the result
+// is then a unit quaternion of no particular meaning. Normalizing the
+// columns and checking IsOrthogonal and a positive Det tells the cases
+// apart.
+func FromMat3(m mat.Mat3) Quat {
+	for j := 0; j < 3; j++ {
+		if l := math.Sqrt(m[j]*m[j] + m[3+j]*m[3+j] + m[6+j]*m[6+j]); l > 0 {
+			m[j], m[3+j], m[6+j] = m[j]/l, m[3+j]/l, m[6+j]/l
+		}
+	}
+	// Extract from the largest of the four possible pivots to avoid
+	// dividing by a small number.
+	var q Quat
+	switch tr := m[0] + m[4] + m[8]; {
+	case tr > 0:
+		s := 2 * math.Sqrt(tr+1)
+		q = Quat{s / 4, (m[7] - m[5]) / s, (m[2] - m[6]) / s, (m[3] - m[1]) / s}
+	case m[0] > m[4] && m[0] > m[8]:
+		s := 2 * math.Sqrt(1+m[0]-m[4]-m[8])
+		q = Quat{(m[7] - m[5]) / s, s / 4, (m[1] + m[3]) / s, (m[2] + m[6]) / s}
+	case m[4] > m[8]:
+		s := 2 * math.Sqrt(1+m[4]-m[0]-m[8])
+		q = Quat{(m[2] - m[6]) / s, (m[1] + m[3]) / s, s / 4, (m[5] + m[7]) / s}
+	default:
+		s := 2 * math.Sqrt(1+m[8]-m[0]-m[4])
+		q = Quat{(m[3] - m[1]) / s, (m[2] + m[6]) / s, (m[5] + m[7]) / s, s / 4}
+	}
+	if q.W < 0 {
+		q = Quat{-q.W, -q.X, -q.Y, -q.Z}
+	}
+	return q.Normalize()
+}
+
+// FromMat4 returns the unit quaternion of the rotation part of the affine
+// transform m. Its linear part must meet the conditions of FromMat3, so
+// per-axis scale is allowed but shear is not.
+func FromMat4(m mat.Mat4) Quat {
+	return FromMat3(m.Mat3())
+}
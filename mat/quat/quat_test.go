@@ -0,0 +1,192 @@
+package quat
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/anon-org/ds/mat"
+)
+
+const tol = 1e-9
+
+func approx(a, b []float64, eps float64) bool {
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > eps {
+			return false
+		}
+	}
+	return true
+}
+
+// sameRotation reports whether q and r are equal up to sign.
+func sameRotation(q, r Quat) bool {
+	return math.Abs(math.Abs(q.Dot(r))-1) < tol
+}
+
+func randomAxis(rng *rand.Rand) mat.Vec3 {
+	return mat.Vec3{rng.NormFloat64(), rng.NormFloat64(), rng.NormFloat64()}
+}
+
+func TestAgainstRotation3(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for iter := 0; iter < 500; iter++ {
+		axis, theta := randomAxis(rng), rng.Float64()*20-10
+		q := FromAxisAngle(axis, theta)
+		if math.Abs(q.Norm()-1) > tol {
+			t.Fatalf("FromAxisAngle(%v, %v) has norm %v", axis, theta, q.Norm())
+		}
+		m := mat.Rotation3(axis, theta)
+		if got := q.Mat3(); !approx(got[:], m[:], tol) {
+			t.Fatalf("Mat3 of %v = %v, want %v", q, got, m)
+		}
+		if got, want := q.Mat4(), m.Mat4(); !approx(got[:], want[:], tol) {
+			t.Fatalf("Mat4 of %v = %v, want %v", q, got, want)
+		}
+		v := randomAxis(rng)
+		if got, want := q.Rotate(v), m.MulVec(v); !approx(got[:], want[:], tol) {
+			t.Fatalf("%v.Rotate(%v) = %v, want %v", q, v, got, want)
+		}
+	}
+}
+
+func TestMulComposes(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for iter := 0; iter < 500; iter++ {
+		a := FromAxisAngle(randomAxis(rng), rng.Float64()*10)
+		b := FromAxisAngle(randomAxis(rng), rng.Float64()*10)
+		if got, want := a.Mul(b).Mat3(), a.Mat3().Mul(b.Mat3()); !approx(got[:], want[:], tol) {
+			t.Fatalf("(%v·%v).Mat3() = %v, want %v", a, b, got, want)
+		}
+		v := randomAxis(rng)
+		if got, want := a.Mul(b).Rotate(v), a.Rotate(b.Rotate(v)); !approx(got[:], want[:], tol) {
+			t.Fatalf("(%v·%v).Rotate(%v) = %v, want %v", a, b, v, got, want)
+		}
+	}
+	if q := FromAxisAngle(mat.Vec3{1, 2, 3}, 1); q.Mul(Identity()) != q || Identity().Mul(q) != q {
+		t.Fatal("multiplying by the identity changed the quaternion")
+	}
+}
+
+func TestFromMat3(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	for iter := 0; iter < 500; iter++ {
+		q := FromAxisAngle(randomAxis(rng), rng.Float64()*20-10)
+		got := FromMat3(q.Mat3())
+		if !sameRotation(got, q) || got.W < 0 {
+			t.Fatalf("FromMat3(%v.Mat3()) = %v", q, got)
+		}
+		if h := FromMat4(mat.Affine4(q.Mat3(), randomAxis(rng))); !sameRotation(h, q) {
+			t.Fatalf("FromMat4 of %v with translation = %v", q, h)
+		}
+		// Large and small scales would otherwise push the pivots out of
+		// range and yield NaN.
+		scale := mat.Scaling3(mat.Vec3{10, 0.1, 3})
+		if h := FromMat4(mat.Affine4(q.Mat3().Mul(scale), randomAxis(rng))); !sameRotation(h, q) {
+			t.Fatalf("FromMat4 of %v with scale and translation = %v", q, h)
+		}
+	}
+	// Half turns have a zero trace-derived W and exercise each of the
+	// diagonal pivots.
+	for _, axis := range []mat.Vec3{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}, {1, 1, 0}, {0, 1, 1}, {1, 0, 1}} {
+		for _, theta := range []float64{math.Pi, math.Pi - 1e-3, -math.Pi + 1e-3} {
+			q := FromAxisAngle(axis, theta)
+			if got := FromMat3(mat.Rotation3(axis, theta)); !sameRotation(got, q) {
+				t.Errorf("FromMat3 of rotation by %v about %v = %v, want ±%v", theta, axis, got, q)
+			}
+		}
+	}
+	if got := FromMat3(mat.Identity3()); got != Identity() {
+		t.Errorf("FromMat3(identity) = %v", got)
+	}
+}
+
+func TestAxisAngle(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	for iter := 0; iter < 500; iter++ {
+		axis, theta := randomAxis(rng).Normalize(), rng.Float64()*2*math.Pi
+		gotAxis, gotTheta := FromAxisAngle(axis, theta).AxisAngle()
+		if !approx(gotAxis[:], axis[:], 1e-6) || math.Abs(gotTheta-theta) > 1e-6 {
+			t.Fatalf("AxisAngle of (%v, %v) = (%v, %v)", axis, theta, gotAxis, gotTheta)
+		}
+	}
+	if axis, theta := Identity().AxisAngle(); axis != (mat.Vec3{1, 0, 0}) || theta != 0 {
+		t.Errorf("Identity().AxisAngle() = %v, %v", axis, theta)
+	}
+	if FromAxisAngle(mat.Vec3{}, 1) != Identity() {
+		t.Error("rotation about the zero axis is not the identity")
+	}
+}
+
+func TestInverse(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	for iter := 0; iter < 200; iter++ {
+		q := Quat{rng.NormFloat64(), rng.NormFloat64(), rng.NormFloat64(), rng.NormFloat64()}
+		inv, ok := q.Inverse()
+		if !ok {
+			t.Fatalf("Inverse(%v) reported zero", q)
+		}
+		p := q.Mul(inv)
+		if !approx([]float64{p.W, p.X, p.Y, p.Z}, []float64{1, 0, 0, 0}, tol) {
+			t.Fatalf("%v·%v = %v, want identity", q, inv, p)
+		}
+		u := q.Normalize()
+		if inv, _ := u.Inverse(); !approx([]float64{inv.W, inv.X, inv.Y, inv.Z}, []float64{u.W, -u.X, -u.Y, -u.Z}, tol) {
+			t.Fatalf("inverse of unit %v = %v, want its conjugate", u, inv)
+		}
+	}
+	if q, ok := (Quat{}).Inverse(); ok || q != (Quat{}) {
+		t.Errorf("Inverse of zero = %v, %v", q, ok)
+	}
+	if (Quat{}).Normalize() != Identity() {
+		t.Error("Normalize of zero is not the identity")
+	}
+}
+
+func TestSlerp(t *testing.T) {
+	rng := rand.New(rand.NewSource(6))
+	for iter := 0; iter < 500; iter++ {
+		axis := randomAxis(rng)
+		a, b := rng.Float64()*3, rng.Float64()*3
+		qa, qb := FromAxisAngle(axis, a), FromAxisAngle(axis, b)
+		if got := Slerp(qa, qb, 0); !sameRotation(got, qa) {
+			t.Fatalf("Slerp(t=0) = %v, want %v", got, qa)
+		}
+		if got := Slerp(qa, qb, 1); !sameRotation(got, qb) {
+			t.Fatalf("Slerp(t=1) = %v, want %v", got, qb)
+		}
+		// About a shared axis, slerp interpolates the angle linearly.
+		s := rng.Float64()
+		if got, want := Slerp(qa, qb, s), FromAxisAngle(axis, a+s*(b-a)); !sameRotation(got, want) {
+			t.Fatalf("Slerp(%v, %v, %v) = %v, want %v", a, b, s, got, want)
+		}
+	}
+
+	// The shortest arc from a to -b is the same as to b.
+	a := FromAxisAngle(mat.Vec3{0, 0, 1}, 0.2)
+	b := FromAxisAngle(mat.Vec3{0, 0, 1}, 1)
+	neg := Quat{-b.W, -b.X, -b.Y, -b.Z}
+	if got, want := Slerp(a, neg, 0.5), Slerp(a, b, 0.5); !sameRotation(got, want) {
+		t.Errorf("Slerp towards -b = %v, want %v", got, want)
+	}
+
+	// Nearly parallel inputs take the linear fallback and stay unit length.
+	c := FromAxisAngle(mat.Vec3{0, 0, 1}, 0.2+1e-6)
+	if got := Slerp(a, c, 0.5); math.Abs(got.Norm()-1) > tol || !sameRotation(got, FromAxisAngle(mat.Vec3{0, 0, 1}, 0.2+5e-7)) {
+		t.Errorf("Slerp of nearly equal rotations = %v", got)
+	}
+}
+
+func TestNoDrift(t *testing.T) {
+	step := FromAxisAngle(mat.Vec3{1, 2, 3}, 0.001)
+	q := Identity()
+	for i := 0; i < 100000; i++ {
+		q = step.Mul(q).Normalize()
+	}
+	if math.Abs(q.Norm()-1) > tol {
+		t.Fatalf("norm after 100000 steps = %v", q.Norm())
+	}
+	if want := FromAxisAngle(mat.Vec3{1, 2, 3}, 100); math.Abs(q.Dot(want)) < 1-1e-6 {
+		t.Fatalf("after 100000 steps q = %v, want %v", q, want)
+	}
+}
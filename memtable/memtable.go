@@ -0,0 +1,176 @@
+// Package memtable provides the in-memory building blocks of a
+// log-structured merge (LSM) storage engine: a skip-list memtable that can
+// be flushed to a sorted run, a reader for such runs, and a k-way merge
+// iterator that combines runs into a single sorted stream.
+//
+// Keys are byte strings compared with bytes.Compare. Deletions are recorded
+// as tombstones so that they shadow older runs until compaction drops them.
+//
+// A Memtable given a metrics.Recorder reports the counters "inserts" (new
+// keys), "puts", "deletes" and "bytes", the last being the change in
+// SizeBytes.
+package memtable
+
+import (
+	"bytes"
+	"math/rand"
+	"unsafe"
+
+	"github.com/anon-org/ds/metrics"
+)
+
+const maxLevel = 24
+
+const ptrSize = int(unsafe.Sizeof(uintptr(0)))
+
+type node struct {
+	key     []byte
+	value   []byte
+	deleted bool
+	next    []*node
+}
+
+// Memtable is a sorted, mutable map from keys to values backed by a skip
+// list. It is not safe for concurrent use.
+type Memtable struct {
+	head  *node
+	level int
+	len   int
+	bytes int
+	rng   *rand.Rand
+	rec   metrics.Recorder
+}
+
+// New returns an empty memtable.
+func New() *Memtable {
+	return &Memtable{
+		head:  &node{next: make([]*node, maxLevel)},
+		level: 1,
+		rng:   rand.New(rand.NewSource(1)),
+	}
+}
+
+// SetRecorder directs the memtable's counters to r. A nil r disables them.
+func (m *Memtable) SetRecorder(r metrics.Recorder) {
+	m.rec = r
+}
+
+func (m *Memtable) record(name string, delta int) {
+	if m.rec != nil {
+		m.rec.Add(name, int64(delta))
+	}
+}
+
+// Len returns the number of entries, tombstones included.
+func (m *Memtable) Len() int {
+	return m.len
+}
+
+// SizeBytes returns the total size of stored keys and values, the usual
+// trigger for flushing a memtable.
+func (m *Memtable) SizeBytes() int {
+	return m.bytes
+}
+
+// Sizeof returns the approximate heap usage of the memtable in bytes. Unlike
+// SizeBytes it includes the skip-list nodes and their forward pointers.
+func (m *Memtable) Sizeof() int {
+	size := int(unsafe.Sizeof(*m)) + int(unsafe.Sizeof(node{})) + maxLevel*ptrSize
+	for x := m.head.next[0]; x != nil; x = x.next[0] {
+		size += int(unsafe.Sizeof(*x)) + cap(x.next)*ptrSize + cap(x.key) + cap(x.value)
+	}
+	return size
+}
+
+// seek returns the first node with key ≥ key and fills update with the
+// rightmost node before it on every level.
+func (m *Memtable) seek(key []byte, update []*node) *node {
+	x := m.head
+	for l := m.level - 1; l >= 0; l-- {
+		for x.next[l] != nil && bytes.Compare(x.next[l].key, key) < 0 {
+			x = x.next[l]
+		}
+		if update != nil {
+			update[l] = x
+		}
+	}
+	return x.next[0]
+}
+
+// Put stores value under key. The memtable keeps its own copies of both.
+func (m *Memtable) Put(key, value []byte) {
+	m.set(key, bytes.Clone(value), false)
+}
+
+// Delete records a tombstone for key.
+func (m *Memtable) Delete(key []byte) {
+	m.set(key, nil, true)
+}
+
+func (m *Memtable) set(key, value []byte, deleted bool) {
+	if deleted {
+		m.record("deletes", 1)
+	} else {
+		m.record("puts", 1)
+	}
+	var update [maxLevel]*node
+	x := m.seek(key, update[:])
+	if x != nil && bytes.Equal(x.key, key) {
+		m.bytes += len(value) - len(x.value)
+		m.record("bytes", len(value)-len(x.value))
+		x.value, x.deleted = value, deleted
+		return
+	}
+
+	lvl := 1
+	for lvl < maxLevel && m.rng.Intn(4) == 0 {
+		lvl++
+	}
+	for ; m.level < lvl; m.level++ {
+		update[m.level] = m.head
+	}
+	n := &node{key: bytes.Clone(key), value: value, deleted: deleted, next: make([]*node, lvl)}
+	for l := 0; l < lvl; l++ {
+		n.next[l] = update[l].next[l]
+		update[l].next[l] = n
+	}
+	m.len++
+	m.bytes += len(key) + len(value)
+	m.record("inserts", 1)
+	m.record("bytes", len(key)+len(value))
+}
+
+// Get returns the value stored under key. found reports whether the
+// memtable has an entry for key at all; deleted reports whether that entry
+// is a tombstone, in which case older runs must not be consulted.
+func (m *Memtable) Get(key []byte) (value []byte, deleted, found bool) {
+	x := m.seek(key, nil)
+	if x == nil || !bytes.Equal(x.key, key) {
+		return nil, false, false
+	}
+	return x.value, x.deleted, true
+}
+
+// Iterator returns an iterator over the entries in key order. The memtable
+// must not be modified while the iterator is in use.
+func (m *Memtable) Iterator() Iterator {
+	return &memIterator{next: m.head.next[0]}
+}
+
+type memIterator struct {
+	cur, next *node
+}
+
+func (it *memIterator) Next() bool {
+	it.cur = it.next
+	if it.cur == nil {
+		return false
+	}
+	it.next = it.cur.next[0]
+	return true
+}
+
+func (it *memIterator) Key() []byte   { return it.cur.key }
+func (it *memIterator) Value() []byte { return it.cur.value }
+func (it *memIterator) Deleted() bool { return it.cur.deleted }
+func (it *memIterator) Err() error    { return nil }
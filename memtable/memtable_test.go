@@ -0,0 +1,300 @@
+package memtable
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+	"unsafe"
+
+	"github.com/anon-org/ds/metrics"
+)
+
+type entry struct {
+	value   string
+	deleted bool
+}
+
+// collect drains it into a slice of key/entry pairs.
+func collect(t *testing.T, it Iterator) ([]string, map[string]entry) {
+	t.Helper()
+	var keys []string
+	m := map[string]entry{}
+	for it.Next() {
+		k := string(it.Key())
+		keys = append(keys, k)
+		m[k] = entry{string(it.Value()), it.Deleted()}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return keys, m
+}
+
+func sortedKeys(m map[string]entry) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func randomOps(r *rand.Rand, mt *Memtable, model map[string]entry, n int) {
+	for i := 0; i < n; i++ {
+		k := fmt.Sprintf("k%03d", r.Intn(200))
+		if r.Intn(4) == 0 {
+			mt.Delete([]byte(k))
+			model[k] = entry{"", true}
+		} else {
+			v := fmt.Sprint(r.Intn(1000))
+			mt.Put([]byte(k), []byte(v))
+			model[k] = entry{v, false}
+		}
+	}
+}
+
+func TestMemtableAgainstMap(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	mt := New()
+	model := map[string]entry{}
+	randomOps(r, mt, model, 3000)
+
+	if mt.Len() != len(model) {
+		t.Fatalf("Len() = %d, want %d", mt.Len(), len(model))
+	}
+	size := 0
+	for k, e := range model {
+		size += len(k) + len(e.value)
+		v, deleted, found := mt.Get([]byte(k))
+		if !found || deleted != e.deleted || string(v) != e.value {
+			t.Fatalf("Get(%q) = %q, %v, %v; want %+v", k, v, deleted, found, e)
+		}
+	}
+	if mt.SizeBytes() != size {
+		t.Fatalf("SizeBytes() = %d, want %d", mt.SizeBytes(), size)
+	}
+	if _, _, found := mt.Get([]byte("missing")); found {
+		t.Fatal("Get found a missing key")
+	}
+	keys, _ := collect(t, mt.Iterator())
+	if !slices.Equal(keys, sortedKeys(model)) {
+		t.Fatalf("Iterator keys out of order")
+	}
+}
+
+func TestRunRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	mt := New()
+	model := map[string]entry{}
+	randomOps(r, mt, model, 1000)
+	mt.Put([]byte("empty"), nil)
+	model["empty"] = entry{"", false}
+
+	var buf bytes.Buffer
+	if err := mt.FlushSorted(&buf); err != nil {
+		t.Fatal(err)
+	}
+	keys, got := collect(t, NewRunReader(&buf))
+	if !slices.Equal(keys, sortedKeys(model)) {
+		t.Fatalf("run keys = %q", keys)
+	}
+	for k, e := range model {
+		if got[k] != e {
+			t.Fatalf("run entry %q = %+v, want %+v", k, got[k], e)
+		}
+	}
+}
+
+func TestRunReaderTruncated(t *testing.T) {
+	mt := New()
+	mt.Put([]byte("key"), []byte("value"))
+	var buf bytes.Buffer
+	if err := mt.FlushSorted(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	for n := 1; n < len(data); n++ {
+		rr := NewRunReader(bytes.NewReader(data[:n]))
+		for rr.Next() {
+		}
+		if !errors.Is(rr.Err(), ErrCorruptRun) {
+			t.Fatalf("truncated to %d bytes: Err() = %v, want ErrCorruptRun", n, rr.Err())
+		}
+	}
+}
+
+func TestMergeAgainstLayeredMaps(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	const layers = 4
+	models := make([]map[string]entry, layers)
+	iters := make([]Iterator, layers)
+	for i := range models {
+		mt := New()
+		models[i] = map[string]entry{}
+		randomOps(r, mt, models[i], 300)
+		// Alternate between memtables and flushed runs as inputs.
+		if i%2 == 0 {
+			iters[i] = mt.Iterator()
+		} else {
+			var buf bytes.Buffer
+			if err := mt.FlushSorted(&buf); err != nil {
+				t.Fatal(err)
+			}
+			iters[i] = NewRunReader(&buf)
+		}
+	}
+	// iters[0] is newest, so it wins on shared keys.
+	want := map[string]entry{}
+	for i := layers - 1; i >= 0; i-- {
+		for k, e := range models[i] {
+			want[k] = e
+		}
+	}
+
+	keys, got := collect(t, Merge(iters...))
+	if !slices.Equal(keys, sortedKeys(want)) {
+		t.Fatalf("merged keys differ")
+	}
+	for k, e := range want {
+		if got[k] != e {
+			t.Fatalf("merged %q = %+v, want %+v", k, got[k], e)
+		}
+	}
+}
+
+func TestMergeDropTombstones(t *testing.T) {
+	newer, older := New(), New()
+	older.Put([]byte("a"), []byte("1"))
+	older.Put([]byte("b"), []byte("2"))
+	newer.Delete([]byte("a"))
+	newer.Put([]byte("c"), []byte("3"))
+	keys, _ := collect(t, Merge(newer.Iterator(), older.Iterator()).DropTombstones())
+	if !slices.Equal(keys, []string{"b", "c"}) {
+		t.Fatalf("keys = %q, want [b c]", keys)
+	}
+}
+
+func TestMergeReportsInputErrors(t *testing.T) {
+	bad := NewRunReader(bytes.NewReader([]byte{5, 'a'}))
+	m := Merge(New().Iterator(), bad)
+	for m.Next() {
+	}
+	if !errors.Is(m.Err(), ErrCorruptRun) {
+		t.Fatalf("Err() = %v, want ErrCorruptRun", m.Err())
+	}
+}
+
+func TestMergeClose(t *testing.T) {
+	a, b := New(), New()
+	for _, k := range []string{"a", "c", "e"} {
+		a.Put([]byte(k), []byte("new"))
+		b.Put([]byte(k), []byte("old"))
+	}
+	b.Put([]byte("b"), []byte("old"))
+	m := Merge(a.Iterator(), b.Iterator())
+	if !m.Next() || string(m.Key()) != "a" || string(m.Value()) != "new" {
+		t.Fatalf("first entry = %q=%q, want a=new", m.Key(), m.Value())
+	}
+	m.Close()
+	if m.Next() {
+		t.Fatalf("Next after Close returned %q", m.Key())
+	}
+	m.Close()
+	if err := m.Err(); err != nil {
+		t.Fatalf("Err() = %v after Close", err)
+	}
+	unused := Merge(a.Iterator())
+	unused.Close()
+	if unused.Next() {
+		t.Fatal("Next on a merge closed before starting returned true")
+	}
+}
+
+func TestRunReaderHugeLength(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"max uint64 key":   {0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+		"1 TiB key":        {0x80, 0x80, 0x80, 0x80, 0x80, 0x20, 'a'},
+		"max uint64 value": {1, 'k', 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+		"varint overflow":  {0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+	} {
+		rr := NewRunReader(bytes.NewReader(data))
+		if rr.Next() {
+			t.Errorf("%s: Next() = true", name)
+		}
+		if !errors.Is(rr.Err(), ErrCorruptRun) {
+			t.Errorf("%s: Err() = %v, want ErrCorruptRun", name, rr.Err())
+		}
+	}
+}
+
+func TestRunReaderLongValue(t *testing.T) {
+	mt := New()
+	long := bytes.Repeat([]byte("0123456789"), 3*readChunk/10+7)
+	mt.Put([]byte("k"), long)
+	var buf bytes.Buffer
+	if err := mt.FlushSorted(&buf); err != nil {
+		t.Fatal(err)
+	}
+	rr := NewRunReader(&buf)
+	if !rr.Next() || !bytes.Equal(rr.Value(), long) {
+		t.Fatalf("long value did not round-trip: %v", rr.Err())
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	var c metrics.Counters
+	mt := New()
+	mt.SetRecorder(&c)
+	r := rand.New(rand.NewSource(6))
+	puts, deletes := 0, 0
+	for i := 0; i < 2000; i++ {
+		k := []byte(fmt.Sprint(r.Intn(300)))
+		if r.Intn(4) == 0 {
+			mt.Delete(k)
+			deletes++
+		} else {
+			mt.Put(k, bytes.Repeat([]byte{'v'}, r.Intn(20)))
+			puts++
+		}
+	}
+	for name, want := range map[string]int{
+		"puts":    puts,
+		"deletes": deletes,
+		"inserts": mt.Len(),
+		"bytes":   mt.SizeBytes(),
+	} {
+		if got := c.Get(name); got != int64(want) {
+			t.Errorf("%s = %d, want %d", name, got, want)
+		}
+	}
+}
+
+func TestSizeof(t *testing.T) {
+	mt := New()
+	empty := mt.Sizeof()
+	r := rand.New(rand.NewSource(7))
+	// Eight-byte keys and sixteen-byte values fill their allocations
+	// exactly, so the copies' capacities match SizeBytes.
+	value := bytes.Repeat([]byte("v"), 16)
+	for i := 0; i < 3000; i++ {
+		k := []byte(fmt.Sprintf("key%05d", r.Intn(1000)))
+		if r.Intn(5) == 0 {
+			mt.Delete(k)
+		} else {
+			mt.Put(k, value)
+		}
+	}
+	links := 0
+	for l := 0; l < maxLevel; l++ {
+		for x := mt.head.next[l]; x != nil; x = x.next[l] {
+			links++
+		}
+	}
+	want := empty + mt.SizeBytes() + mt.Len()*int(unsafe.Sizeof(node{})) + links*ptrSize
+	if got := mt.Sizeof(); got != want {
+		t.Fatalf("Sizeof() = %d for %d entries and %d links, want %d", got, mt.Len(), links, want)
+	}
+}
@@ -0,0 +1,107 @@
+package memtable
+
+import (
+	"bytes"
+	"iter"
+
+	"github.com/anon-org/ds/merge"
+)
+
+// MergeIterator merges several sorted iterators into one sorted stream.
+// When more than one input holds the same key, the entry from the input
+// listed first wins and the others are skipped, so inputs should be passed
+// newest first. The merge itself is merge.K with Dedup, which keeps the
+// first of equal elements in input order.
+type MergeIterator struct {
+	iters          []Iterator
+	started        bool
+	next           func() (record, bool)
+	stop           func()
+	dropTombstones bool
+	cur            record
+	err            error
+}
+
+// record is one input entry, copied out of its iterator since inputs may
+// reuse their buffers.
+type record struct {
+	key, value []byte
+	deleted    bool
+}
+
+// Merge returns an iterator over the union of iters, ordered newest first.
+func Merge(iters ...Iterator) *MergeIterator {
+	return &MergeIterator{iters: iters}
+}
+
+// DropTombstones makes the iterator skip deleted entries instead of
+// yielding them, which is what a compaction into the oldest run wants. It
+// must be called before the first call to Next.
+func (m *MergeIterator) DropTombstones() *MergeIterator {
+	m.dropTombstones = true
+	return m
+}
+
+// Next advances to the next distinct key.
+func (m *MergeIterator) Next() bool {
+	if !m.started {
+		m.started = true
+		m.next, m.stop = iter.Pull(m.merged())
+	}
+	for m.err == nil && m.next != nil {
+		r, ok := m.next()
+		if !ok || m.err != nil {
+			break
+		}
+		if r.deleted && m.dropTombstones {
+			continue
+		}
+		m.cur = r
+		return true
+	}
+	m.Close()
+	return false
+}
+
+// Close releases the inputs. It is called automatically once Next returns
+// false, so it is only needed when abandoning a merge early.
+func (m *MergeIterator) Close() {
+	m.started = true
+	if m.stop != nil {
+		m.stop()
+		m.next, m.stop = nil, nil
+	}
+}
+
+// merged adapts every input to a sequence and merges them by key. An input
+// that fails ends its sequence early and records the error for Next.
+func (m *MergeIterator) merged() iter.Seq[record] {
+	seqs := make([]iter.Seq[record], len(m.iters))
+	for i, it := range m.iters {
+		seqs[i] = func(yield func(record) bool) {
+			for it.Next() {
+				r := record{bytes.Clone(it.Key()), bytes.Clone(it.Value()), it.Deleted()}
+				if !yield(r) {
+					return
+				}
+			}
+			if err := it.Err(); err != nil && m.err == nil {
+				m.err = err
+			}
+		}
+	}
+	less := func(a, b record) bool { return bytes.Compare(a.key, b.key) < 0 }
+	return merge.K(seqs, less, merge.Dedup())
+}
+
+// Key returns the current key.
+func (m *MergeIterator) Key() []byte { return m.cur.key }
+
+// Value returns the current value.
+func (m *MergeIterator) Value() []byte { return m.cur.value }
+
+// Deleted reports whether the current entry is a tombstone.
+func (m *MergeIterator) Deleted() bool { return m.cur.deleted }
+
+// Err returns the first error reported by any input.
+func (m *MergeIterator) Err() error { return m.err }
@@ -0,0 +1,133 @@
+package memtable
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"slices"
+)
+
+// ErrCorruptRun is returned when a sorted run cannot be decoded.
+var ErrCorruptRun = errors.New("memtable: corrupt run")
+
+// Iterator walks entries in ascending key order. Next must be called before
+// the first entry is available; Key and Value are only valid until the
+// following call to Next.
+type Iterator interface {
+	Next() bool
+	Key() []byte
+	Value() []byte
+	Deleted() bool
+	// Err returns the first error encountered, once Next has returned
+	// false.
+	Err() error
+}
+
+// FlushSorted writes every entry, tombstones included, to w as a sorted
+// run readable with NewRunReader.
+func (m *Memtable) FlushSorted(w io.Writer) error {
+	return WriteRun(w, m.Iterator())
+}
+
+// WriteRun drains it into w as a sorted run. Each entry is encoded as the
+// uvarint key length, the key, then the uvarint value length plus one (zero
+// marks a tombstone) followed by the value.
+func WriteRun(w io.Writer, it Iterator) error {
+	// bufio.Writer keeps the first write error and reports it from Flush.
+	bw := bufio.NewWriter(w)
+	var buf [binary.MaxVarintLen64]byte
+	for it.Next() {
+		key := it.Key()
+		bw.Write(buf[:binary.PutUvarint(buf[:], uint64(len(key)))])
+		bw.Write(key)
+		if it.Deleted() {
+			bw.WriteByte(0)
+			continue
+		}
+		value := it.Value()
+		bw.Write(buf[:binary.PutUvarint(buf[:], uint64(len(value))+1)])
+		bw.Write(value)
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// RunReader iterates over a sorted run written by WriteRun.
+type RunReader struct {
+	r       *bufio.Reader
+	key     []byte
+	value   []byte
+	deleted bool
+	err     error
+}
+
+// NewRunReader returns an iterator over the run read from r.
+func NewRunReader(r io.Reader) *RunReader {
+	return &RunReader{r: bufio.NewReader(r)}
+}
+
+// Next advances to the next entry.
+func (rr *RunReader) Next() bool {
+	if rr.err != nil {
+		return false
+	}
+	n, err := binary.ReadUvarint(rr.r)
+	if err != nil {
+		if err != io.EOF {
+			rr.err = ErrCorruptRun
+		}
+		return false
+	}
+	if rr.key, err = rr.read(n, rr.key); err != nil {
+		return false
+	}
+	if n, err = binary.ReadUvarint(rr.r); err != nil {
+		rr.err = ErrCorruptRun
+		return false
+	}
+	rr.deleted = n == 0
+	if rr.deleted {
+		rr.value = rr.value[:0]
+		return true
+	}
+	rr.value, err = rr.read(n-1, rr.value)
+	return err == nil
+}
+
+// readChunk bounds how far read grows its buffer ahead of the bytes
+// actually received.
+const readChunk = 64 << 10
+
+// read reads n bytes into buf, reusing its storage. n comes from the run
+// itself, so the buffer grows in chunks as data arrives instead of being
+// sized up front; a corrupt length then costs at most one chunk of memory
+// before the short read is detected.
+func (rr *RunReader) read(n uint64, buf []byte) ([]byte, error) {
+	buf = buf[:0]
+	for n > 0 {
+		chunk := int(min(n, readChunk))
+		start := len(buf)
+		buf = slices.Grow(buf, chunk)[:start+chunk]
+		if _, err := io.ReadFull(rr.r, buf[start:]); err != nil {
+			rr.err = ErrCorruptRun
+			return nil, rr.err
+		}
+		n -= uint64(chunk)
+	}
+	return buf, nil
+}
+
+// Key returns the current key.
+func (rr *RunReader) Key() []byte { return rr.key }
+
+// Value returns the current value. It is empty for tombstones.
+func (rr *RunReader) Value() []byte { return rr.value }
+
+// Deleted reports whether the current entry is a tombstone.
+func (rr *RunReader) Deleted() bool { return rr.deleted }
+
+// Err returns the first decoding error, if any.
+func (rr *RunReader) Err() error { return rr.err }
@@ -0,0 +1,107 @@
+// Package merge combines sorted sequences.
+package merge
+
+import (
+	"container/heap"
+	"iter"
+)
+
+type config struct {
+	dedup bool
+	limit int // negative means unlimited
+}
+
+// Option configures K.
+type Option func(*config)
+
+// Dedup drops every element that compares equal to the element yielded
+// just before it, so equal elements from different inputs (or repeated
+// within one input) are yielded once. The first input wins ties.
+func Dedup() Option {
+	return func(c *config) { c.dedup = true }
+}
+
+// Limit stops the merge after n elements have been yielded. Inputs are not
+// read past what is needed to produce them.
+func Limit(n int) Option {
+	return func(c *config) { c.limit = max(n, 0) }
+}
+
+// K merges sequences that are each sorted by less into one sorted
+// sequence, using a min-heap over the inputs' current heads. Equal elements
+// are yielded in input order, making the merge stable. Inputs are consumed
+// lazily and released as soon as the consumer stops ranging over the
+// result or the merge ends.
+func K[T any](seqs []iter.Seq[T], less func(a, b T) bool, opts ...Option) iter.Seq[T] {
+	cfg := config{limit: -1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return func(yield func(T) bool) {
+		if cfg.limit == 0 {
+			return
+		}
+		h := &heads[T]{less: less}
+		for i, seq := range seqs {
+			next, stop := iter.Pull(seq)
+			defer stop()
+			if v, ok := next(); ok {
+				h.items = append(h.items, head[T]{value: v, rank: i, next: next})
+			}
+		}
+		heap.Init(h)
+
+		var last T
+		yielded := 0
+		for len(h.items) > 0 {
+			top := &h.items[0]
+			v := top.value
+			if !cfg.dedup || yielded == 0 || less(last, v) || less(v, last) {
+				if !yield(v) {
+					return
+				}
+				last = v
+				yielded++
+				if yielded == cfg.limit {
+					return
+				}
+			}
+			if nv, ok := top.next(); ok {
+				top.value = nv
+				heap.Fix(h, 0)
+			} else {
+				heap.Pop(h)
+			}
+		}
+	}
+}
+
+type head[T any] struct {
+	value T
+	rank  int
+	next  func() (T, bool)
+}
+
+type heads[T any] struct {
+	items []head[T]
+	less  func(a, b T) bool
+}
+
+func (h *heads[T]) Len() int { return len(h.items) }
+func (h *heads[T]) Less(i, j int) bool {
+	a, b := &h.items[i], &h.items[j]
+	if h.less(a.value, b.value) {
+		return true
+	}
+	if h.less(b.value, a.value) {
+		return false
+	}
+	return a.rank < b.rank
+}
+func (h *heads[T]) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *heads[T]) Push(x any)    { h.items = append(h.items, x.(head[T])) }
+func (h *heads[T]) Pop() any {
+	x := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return x
+}
@@ -0,0 +1,91 @@
+package merge
+
+import (
+	"iter"
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+)
+
+type item struct{ key, src int }
+
+func lessKey(a, b item) bool { return a.key < b.key }
+
+func TestKAgainstStableSort(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 300; trial++ {
+		k := r.Intn(6)
+		var seqs []iter.Seq[item]
+		var all []item
+		for i := 0; i < k; i++ {
+			var s []item
+			for j := r.Intn(12); j > 0; j-- {
+				s = append(s, item{r.Intn(10), i})
+			}
+			sort.SliceStable(s, func(a, b int) bool { return s[a].key < s[b].key })
+			seqs = append(seqs, slices.Values(s))
+			all = append(all, s...)
+		}
+		want := slices.Clone(all)
+		sort.SliceStable(want, func(a, b int) bool { return want[a].key < want[b].key })
+		if got := slices.Collect(K(seqs, lessKey)); !slices.Equal(got, want) {
+			t.Fatalf("K = %v, want %v", got, want)
+		}
+
+		var dedup []item
+		for _, x := range want {
+			if len(dedup) == 0 || dedup[len(dedup)-1].key != x.key {
+				dedup = append(dedup, x)
+			}
+		}
+		if got := slices.Collect(K(seqs, lessKey, Dedup())); !slices.Equal(got, dedup) {
+			t.Fatalf("K Dedup = %v, want %v", got, dedup)
+		}
+
+		n := r.Intn(len(want) + 2)
+		if got := slices.Collect(K(seqs, lessKey, Limit(n))); !slices.Equal(got, want[:min(n, len(want))]) {
+			t.Fatalf("K Limit(%d) = %v, want %v", n, got, want[:min(n, len(want))])
+		}
+	}
+}
+
+// counted yields 0, 1, 2, ... up to n and records how many values were
+// produced and whether the sequence was released.
+func counted(n int, produced *int, released *bool) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		defer func() { *released = true }()
+		for i := 0; i < n; i++ {
+			*produced++
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+func TestKIsLazy(t *testing.T) {
+	var produced [2]int
+	var released [2]bool
+	seqs := []iter.Seq[int]{
+		counted(1000, &produced[0], &released[0]),
+		counted(1000, &produced[1], &released[1]),
+	}
+	less := func(a, b int) bool { return a < b }
+	for v := range K(seqs, less) {
+		if v == 2 {
+			break
+		}
+	}
+	if produced[0]+produced[1] > 8 {
+		t.Fatalf("inputs produced %v values for 5 outputs", produced)
+	}
+	if !released[0] || !released[1] {
+		t.Fatalf("inputs not released after break: %v", released)
+	}
+
+	produced = [2]int{}
+	if got := slices.Collect(K(seqs, less, Limit(0))); len(got) != 0 || produced != [2]int{} {
+		t.Fatalf("Limit(0) yielded %v and read %v", got, produced)
+	}
+}
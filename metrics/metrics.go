@@ -0,0 +1,83 @@
+// Package metrics defines the instrumentation hook shared by the containers
+// in this module.
+//
+// Instrumented containers accept a Recorder and report named counter
+// deltas to it, such as "inserts", "evictions" or "rebuilds"; the names a
+// container emits are listed in its package documentation. A nil Recorder
+// disables instrumentation at the cost of a single nil check per event.
+//
+// Any type with a matching Add method is a Recorder. *Counters is the
+// in-memory implementation provided here, *expvar.Map satisfies Recorder as
+// is, and a Prometheus CounterVec can be adapted with a one-line wrapper.
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Recorder receives counter increments from instrumented containers. Add
+// may be called from multiple goroutines when a container is shared.
+type Recorder interface {
+	Add(name string, delta int64)
+}
+
+// Counters is an in-memory Recorder. The zero value is ready to use and
+// safe for concurrent use.
+type Counters struct {
+	m sync.Map // name -> *atomic.Int64
+}
+
+// Add increments the counter called name by delta.
+func (c *Counters) Add(name string, delta int64) {
+	v, ok := c.m.Load(name)
+	if !ok {
+		v, _ = c.m.LoadOrStore(name, new(atomic.Int64))
+	}
+	v.(*atomic.Int64).Add(delta)
+}
+
+// Get returns the current value of the counter called name.
+func (c *Counters) Get(name string) int64 {
+	if v, ok := c.m.Load(name); ok {
+		return v.(*atomic.Int64).Load()
+	}
+	return 0
+}
+
+// Snapshot returns the current value of every counter.
+func (c *Counters) Snapshot() map[string]int64 {
+	out := make(map[string]int64)
+	c.m.Range(func(k, v any) bool {
+		out[k.(string)] = v.(*atomic.Int64).Load()
+		return true
+	})
+	return out
+}
+
+// Names returns the names of all counters in sorted order.
+func (c *Counters) Names() []string {
+	var names []string
+	c.m.Range(func(k, _ any) bool {
+		names = append(names, k.(string))
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
+// Prefixed returns a Recorder that forwards to r with prefix prepended to
+// every counter name, for sharing one Recorder among several containers.
+func Prefixed(r Recorder, prefix string) Recorder {
+	return prefixed{r, prefix}
+}
+
+type prefixed struct {
+	r      Recorder
+	prefix string
+}
+
+func (p prefixed) Add(name string, delta int64) {
+	p.r.Add(p.prefix+name, delta)
+}
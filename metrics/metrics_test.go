@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"expvar"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+)
+
+var _ Recorder = (*expvar.Map)(nil)
+
+func TestCounters(t *testing.T) {
+	var c Counters
+	c.Add("b", 2)
+	c.Add("a", 1)
+	c.Add("b", -5)
+	if got := c.Get("b"); got != -3 {
+		t.Fatalf("Get(b) = %d, want -3", got)
+	}
+	if got := c.Get("missing"); got != 0 {
+		t.Fatalf("Get(missing) = %d, want 0", got)
+	}
+	if got := c.Names(); !slices.Equal(got, []string{"a", "b"}) {
+		t.Fatalf("Names() = %v", got)
+	}
+	if got := c.Snapshot(); !maps.Equal(got, map[string]int64{"a": 1, "b": -3}) {
+		t.Fatalf("Snapshot() = %v", got)
+	}
+}
+
+func TestCountersConcurrent(t *testing.T) {
+	var c Counters
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				c.Add("n", 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := c.Get("n"); got != 8000 {
+		t.Fatalf("Get(n) = %d, want 8000", got)
+	}
+}
+
+func TestPrefixed(t *testing.T) {
+	var c Counters
+	r := Prefixed(Prefixed(&c, "db."), "cache.")
+	r.Add("hits", 3)
+	if got := c.Get("db.cache.hits"); got != 3 {
+		t.Fatalf("Get(db.cache.hits) = %d, want 3; have %v", got, c.Snapshot())
+	}
+}
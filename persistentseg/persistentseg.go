@@ -0,0 +1,208 @@
+// Package persistentseg implements a persistent segment tree.
+//
+// Every point update produces a new version of the tree while leaving all
+// earlier versions intact and queryable. An update copies only the O(log n)
+// nodes on the path to the changed leaf, so keeping the full history of m
+// updates over n elements costs O(n + m log n) memory. This makes "range
+// query as of version v" cheap for time-travel analytics over append-only
+// data.
+package persistentseg
+
+import (
+	"math"
+	"math/bits"
+	"slices"
+	"unsafe"
+)
+
+// maxNodes bounds the node count so that int32 child indices cannot wrap.
+// It is a variable so that tests can lower it.
+var maxNodes = math.MaxInt32
+
+// Tree holds every version of a fixed-length sequence. Versions are
+// numbered from 0, the sequence the tree was built from. Nodes are linked
+// by 32-bit indices, which halves the memory of the links; a tree holds at
+// most 2³¹-1 nodes, and New, Set and Reserve panic rather than exceed it.
+type Tree[T any] struct {
+	n        int
+	left     []int32
+	right    []int32
+	agg      []T
+	roots    []int32
+	identity T
+	combine  func(a, b T) T
+}
+
+// Sizeof returns the approximate heap usage of the tree and all its
+// versions in bytes.
+func (t *Tree[T]) Sizeof() int {
+	var zero T
+	return int(unsafe.Sizeof(*t)) + 4*(cap(t.left)+cap(t.right)+cap(t.roots)) +
+		cap(t.agg)*int(unsafe.Sizeof(zero))
+}
+
+// New builds version 0 from values. combine must be associative and
+// identity must be its neutral element.
+func New[T any](values []T, identity T, combine func(a, b T) T) *Tree[T] {
+	t := &Tree[T]{
+		n:        len(values),
+		identity: identity,
+		combine:  combine,
+	}
+	// Node 0 is a shared placeholder so that leaves need no children.
+	t.left = make([]int32, 1, 2*t.n+1)
+	t.right = make([]int32, 1, 2*t.n+1)
+	t.agg = append(make([]T, 0, 2*t.n+1), identity)
+	if t.n == 0 {
+		t.roots = []int32{0}
+		return t
+	}
+	t.roots = []int32{t.build(values, 0, t.n)}
+	return t
+}
+
+// Len returns the length of the sequence, which is the same in every
+// version.
+func (t *Tree[T]) Len() int {
+	return t.n
+}
+
+// Versions returns the number of versions, including version 0.
+func (t *Tree[T]) Versions() int {
+	return len(t.roots)
+}
+
+// Latest returns the number of the most recent version.
+func (t *Tree[T]) Latest() int {
+	return len(t.roots) - 1
+}
+
+// Get returns element i as of version v.
+func (t *Tree[T]) Get(v, i int) T {
+	t.checkIndex(i)
+	node := t.root(v)
+	lo, hi := 0, t.n
+	for hi-lo > 1 {
+		mid := int(uint(lo+hi) >> 1)
+		if i < mid {
+			node, hi = t.left[node], mid
+		} else {
+			node, lo = t.right[node], mid
+		}
+	}
+	return t.agg[node]
+}
+
+// Query aggregates elements [lo, hi) as of version v. An empty range yields
+// the identity.
+func (t *Tree[T]) Query(v, lo, hi int) T {
+	if lo < 0 || hi > t.n || lo > hi {
+		panic("persistentseg: invalid range")
+	}
+	return t.query(t.root(v), 0, t.n, lo, hi)
+}
+
+// Set derives a new version from version v with element i replaced by
+// value, and returns the new version's number. Version v itself is left
+// unchanged.
+func (t *Tree[T]) Set(v, i int, value T) int {
+	t.checkIndex(i)
+	t.roots = append(t.roots, t.set(t.root(v), 0, t.n, i, value))
+	return len(t.roots) - 1
+}
+
+// Update is shorthand for Set on the latest version.
+func (t *Tree[T]) Update(i int, value T) int {
+	return t.Set(t.Latest(), i, value)
+}
+
+// Reserve grows the tree's storage so that n more calls to Set or Update
+// can be made without another allocation. Each call adds one version and
+// ⌈log₂ Len⌉+1 nodes.
+func (t *Tree[T]) Reserve(n int) {
+	if n < 0 {
+		panic("persistentseg: negative reserve")
+	}
+	nodes := 0
+	if t.n > 0 {
+		depth := bits.Len(uint(t.n-1)) + 1
+		if n > (maxNodes-len(t.agg))/depth {
+			panic("persistentseg: too many nodes")
+		}
+		nodes = n * depth
+	}
+	t.left = slices.Grow(t.left, nodes)
+	t.right = slices.Grow(t.right, nodes)
+	t.agg = slices.Grow(t.agg, nodes)
+	t.roots = slices.Grow(t.roots, n)
+}
+
+// ShrinkToFit releases spare capacity left by appending versions, for
+// trees that are done being updated and are only queried from then on.
+func (t *Tree[T]) ShrinkToFit() {
+	t.left = slices.Clip(slices.Clone(t.left))
+	t.right = slices.Clip(slices.Clone(t.right))
+	t.agg = slices.Clip(slices.Clone(t.agg))
+	t.roots = slices.Clip(slices.Clone(t.roots))
+}
+
+func (t *Tree[T]) root(v int) int32 {
+	if v < 0 || v >= len(t.roots) {
+		panic("persistentseg: version out of range")
+	}
+	return t.roots[v]
+}
+
+func (t *Tree[T]) checkIndex(i int) {
+	if i < 0 || i >= t.n {
+		panic("persistentseg: index out of range")
+	}
+}
+
+func (t *Tree[T]) alloc(l, r int32, agg T) int32 {
+	if len(t.agg) >= maxNodes {
+		panic("persistentseg: too many nodes")
+	}
+	t.left = append(t.left, l)
+	t.right = append(t.right, r)
+	t.agg = append(t.agg, agg)
+	return int32(len(t.agg) - 1)
+}
+
+func (t *Tree[T]) build(values []T, lo, hi int) int32 {
+	if hi-lo == 1 {
+		return t.alloc(0, 0, values[lo])
+	}
+	mid := int(uint(lo+hi) >> 1)
+	l := t.build(values, lo, mid)
+	r := t.build(values, mid, hi)
+	return t.alloc(l, r, t.combine(t.agg[l], t.agg[r]))
+}
+
+func (t *Tree[T]) set(node int32, lo, hi, i int, value T) int32 {
+	if hi-lo == 1 {
+		return t.alloc(0, 0, value)
+	}
+	mid := int(uint(lo+hi) >> 1)
+	l, r := t.left[node], t.right[node]
+	if i < mid {
+		l = t.set(l, lo, mid, i, value)
+	} else {
+		r = t.set(r, mid, hi, i, value)
+	}
+	return t.alloc(l, r, t.combine(t.agg[l], t.agg[r]))
+}
+
+func (t *Tree[T]) query(node int32, lo, hi, qlo, qhi int) T {
+	if qlo >= hi || qhi <= lo {
+		return t.identity
+	}
+	if qlo <= lo && hi <= qhi {
+		return t.agg[node]
+	}
+	mid := int(uint(lo+hi) >> 1)
+	return t.combine(
+		t.query(t.left[node], lo, mid, qlo, qhi),
+		t.query(t.right[node], mid, hi, qlo, qhi),
+	)
+}
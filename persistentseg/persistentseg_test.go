@@ -0,0 +1,147 @@
+package persistentseg
+
+import (
+	"math/bits"
+	"math/rand"
+	"testing"
+	"unsafe"
+)
+
+func sum(a, b int) int { return a + b }
+
+func TestRandomAgainstSnapshots(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	const n = 37
+	init := make([]int, n)
+	for i := range init {
+		init[i] = r.Intn(100)
+	}
+	tr := New(init, 0, sum)
+	versions := [][]int{append([]int(nil), init...)}
+	for step := 0; step < 2000; step++ {
+		v := r.Intn(len(versions))
+		if r.Intn(2) == 0 {
+			i, x := r.Intn(n), r.Intn(100)
+			next := append([]int(nil), versions[v]...)
+			next[i] = x
+			if got := tr.Set(v, i, x); got != len(versions) {
+				t.Fatalf("Set returned version %d, want %d", got, len(versions))
+			}
+			versions = append(versions, next)
+			continue
+		}
+		lo := r.Intn(n + 1)
+		hi := lo + r.Intn(n+1-lo)
+		want := 0
+		for _, x := range versions[v][lo:hi] {
+			want += x
+		}
+		if got := tr.Query(v, lo, hi); got != want {
+			t.Fatalf("Query(%d, %d, %d) = %d, want %d", v, lo, hi, got, want)
+		}
+		if lo < n {
+			if got := tr.Get(v, lo); got != versions[v][lo] {
+				t.Fatalf("Get(%d, %d) = %d, want %d", v, lo, got, versions[v][lo])
+			}
+		}
+	}
+	if tr.Versions() != len(versions) || tr.Latest() != len(versions)-1 {
+		t.Fatalf("Versions() = %d, want %d", tr.Versions(), len(versions))
+	}
+}
+
+func TestUpdateLatest(t *testing.T) {
+	tr := New([]int{1, 2, 3}, 0, sum)
+	tr.Update(0, 10)
+	tr.Update(2, 30)
+	for _, tc := range []struct{ v, want int }{{0, 6}, {1, 15}, {2, 42}} {
+		if got := tr.Query(tc.v, 0, 3); got != tc.want {
+			t.Errorf("Query(%d, 0, 3) = %d, want %d", tc.v, got, tc.want)
+		}
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	tr := New(nil, 0, sum)
+	if got := tr.Query(0, 0, 0); got != 0 {
+		t.Fatalf("Query on empty = %d, want 0", got)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Get on empty tree did not panic")
+		}
+	}()
+	tr.Get(0, 0)
+}
+
+func TestReserveAndShrinkToFit(t *testing.T) {
+	for _, n := range []int{1, 2, 5, 8, 33} {
+		tr := New(make([]int, n), 0, sum)
+		tr.Reserve(50)
+		caps := [4]int{cap(tr.left), cap(tr.right), cap(tr.agg), cap(tr.roots)}
+		for i := range 50 {
+			tr.Update(i%n, i)
+		}
+		if got := [4]int{cap(tr.left), cap(tr.right), cap(tr.agg), cap(tr.roots)}; got != caps {
+			t.Fatalf("n=%d: reserved updates reallocated: %v, was %v", n, got, caps)
+		}
+		want := tr.Query(tr.Latest(), 0, n)
+		tr.ShrinkToFit()
+		if cap(tr.agg) != len(tr.agg) || cap(tr.roots) != len(tr.roots) {
+			t.Fatalf("n=%d: ShrinkToFit left spare capacity", n)
+		}
+		if got := tr.Query(tr.Latest(), 0, n); got != want {
+			t.Fatalf("n=%d: Query after ShrinkToFit = %d, want %d", n, got, want)
+		}
+	}
+}
+
+func TestTooManyNodes(t *testing.T) {
+	defer func(n int) { maxNodes = n }(maxNodes)
+	// 8 leaves build 15 nodes plus the placeholder; each update adds 4.
+	maxNodes = 16 + 4*3
+	tr := New(make([]int, 8), 0, sum)
+	for i := range 3 {
+		tr.Update(i, i+1)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("Update past maxNodes did not panic")
+			}
+		}()
+		tr.Update(7, 100)
+	}()
+	for v := 0; v <= 3; v++ {
+		if got, want := tr.Query(v, 0, 8), v*(v+1)/2; got != want {
+			t.Fatalf("version %d sums to %d after the failed update, want %d", v, got, want)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Reserve past maxNodes did not panic")
+		}
+	}()
+	New(make([]int, 8), 0, sum).Reserve(4)
+}
+
+func TestSizeof(t *testing.T) {
+	header := int(unsafe.Sizeof(Tree[int]{}))
+	// Powers of two give every leaf the same depth.
+	for _, n := range []int{1, 2, 8, 64} {
+		tr := New(make([]int, n), 0, sum)
+		const updates = 30
+		for i := range updates {
+			tr.Update(i%n, i)
+		}
+		tr.ShrinkToFit()
+		// 2n-1 nodes for version 0 plus the placeholder, then one node per
+		// level for each update; every node costs two int32 links and an int.
+		nodes := 2*n + updates*(bits.Len(uint(n-1))+1)
+		want := header + nodes*(4+4+int(unsafe.Sizeof(0))) + 4*(updates+1)
+		if got := tr.Sizeof(); got != want {
+			t.Fatalf("n=%d: Sizeof() = %d, want %d", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+// Package prioritycache implements a bounded cache whose eviction policy
+// combines time-to-live expiry, priority classes and LRU order.
+//
+// When room is needed the cache first drops an expired entry, if any;
+// otherwise it evicts the least recently used entry of the lowest non-empty
+// priority class. Entries are tracked in one LRU list per class and a
+// min-heap keyed by expiry time, so every operation runs in O(1) apart from
+// the O(log n) heap maintenance for entries with a TTL.
+//
+// Besides Stats, a cache configured with a metrics.Recorder reports the
+// counters "hits", "misses", "inserts", "evictions" and "expirations" as
+// they happen, for export through expvar or Prometheus.
+package prioritycache
+
+import (
+	"container/heap"
+	"container/list"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/anon-org/ds/metrics"
+)
+
+// Reason tells an eviction callback why an entry left the cache.
+type Reason int
+
+const (
+	// Expired entries outlived their TTL.
+	Expired Reason = iota
+	// Evicted entries were removed to make room for a new one.
+	Evicted
+	// Deleted entries were removed explicitly by Delete or replaced by Set.
+	Deleted
+)
+
+// Stats is a snapshot of the cache's counters.
+type Stats struct {
+	Hits        uint64
+	Misses      uint64
+	Evictions   uint64
+	Expirations uint64
+}
+
+// HitRate returns Hits / (Hits + Misses), or 0 before any lookup.
+func (s Stats) HitRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// Config configures a Cache.
+type Config[K comparable, V any] struct {
+	// Capacity is the maximum number of entries. It must be positive.
+	Capacity int
+	// DefaultTTL applies to entries stored with Set. Zero means entries
+	// never expire.
+	DefaultTTL time.Duration
+	// Priorities is the number of priority classes; priorities passed to
+	// SetWithOptions must lie in [0, Priorities). Class 0 is evicted first.
+	// Zero means a single class.
+	Priorities int
+	// OnEvict, if set, is called for every entry that leaves the cache
+	// other than through Purge. It runs with the cache lock held and must
+	// not call back into the cache.
+	OnEvict func(key K, value V, reason Reason)
+	// Now returns the current time. It defaults to time.Now.
+	Now func() time.Time
+	// Recorder, if set, receives the cache's counters.
+	Recorder metrics.Recorder
+}
+
+type entry[K comparable, V any] struct {
+	key      K
+	value    V
+	priority int
+	expires  time.Time // zero if the entry never expires
+	heapIdx  int       // index in the expiry heap, -1 if not present
+	elem     *list.Element
+}
+
+// expiryHeap orders entries by expiry time.
+type expiryHeap[K comparable, V any] []*entry[K, V]
+
+func (h expiryHeap[K, V]) Len() int           { return len(h) }
+func (h expiryHeap[K, V]) Less(i, j int) bool { return h[i].expires.Before(h[j].expires) }
+func (h expiryHeap[K, V]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIdx = i
+	h[j].heapIdx = j
+}
+
+func (h *expiryHeap[K, V]) Push(x any) {
+	e := x.(*entry[K, V])
+	e.heapIdx = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *expiryHeap[K, V]) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	e.heapIdx = -1
+	return e
+}
+
+// Cache is a size-bounded cache safe for concurrent use.
+type Cache[K comparable, V any] struct {
+	mu      sync.Mutex
+	cfg     Config[K, V]
+	items   map[K]*entry[K, V]
+	classes []*list.List // front = most recently used
+	expiry  expiryHeap[K, V]
+	stats   Stats
+}
+
+// New returns an empty cache. It panics if cfg.Capacity is not positive.
+func New[K comparable, V any](cfg Config[K, V]) *Cache[K, V] {
+	if cfg.Capacity <= 0 {
+		panic("prioritycache: capacity must be positive")
+	}
+	if cfg.Priorities <= 0 {
+		cfg.Priorities = 1
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	c := &Cache[K, V]{
+		cfg:     cfg,
+		items:   make(map[K]*entry[K, V], cfg.Capacity),
+		classes: make([]*list.List, cfg.Priorities),
+	}
+	for i := range c.classes {
+		c.classes[i] = list.New()
+	}
+	return c
+}
+
+// Len returns the number of entries, including expired entries that have
+// not been removed yet.
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+// Stats returns a snapshot of the cache's counters.
+func (c *Cache[K, V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Sizeof returns the approximate heap usage of the cache in bytes. The key
+// index is sized for Capacity from the start, so it is estimated at full
+// capacity.
+func (c *Cache[K, V]) Sizeof() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var key K
+	ptr := int(unsafe.Sizeof(uintptr(0)))
+	// A map slot holds the key, the entry pointer and a control byte, and
+	// tables are kept at most 7/8 full.
+	index := c.cfg.Capacity * (int(unsafe.Sizeof(key)) + ptr + 1) * 8 / 7
+	perEntry := int(unsafe.Sizeof(entry[K, V]{})) + int(unsafe.Sizeof(list.Element{}))
+	return int(unsafe.Sizeof(*c)) + index + len(c.items)*perEntry +
+		len(c.classes)*(ptr+int(unsafe.Sizeof(list.List{}))) + cap(c.expiry)*ptr
+}
+
+// Get returns the value stored under key and marks it recently used.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if ok && c.expired(e, c.cfg.Now()) {
+		c.remove(e, Expired)
+		ok = false
+	}
+	if !ok {
+		c.stats.Misses++
+		c.record("misses")
+		var zero V
+		return zero, false
+	}
+	c.stats.Hits++
+	c.record("hits")
+	c.classes[e.priority].MoveToFront(e.elem)
+	return e.value, true
+}
+
+// Peek returns the value stored under key without updating recency or the
+// hit/miss counters.
+func (c *Cache[K, V]) Peek(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok || c.expired(e, c.cfg.Now()) {
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+// Set stores value under key with the default TTL and priority 0.
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithOptions(key, value, c.cfg.DefaultTTL, 0)
+}
+
+// SetWithOptions stores value under key with the given TTL (zero for no
+// expiry) and priority class. It panics if priority is out of range.
+func (c *Cache[K, V]) SetWithOptions(key K, value V, ttl time.Duration, priority int) {
+	if priority < 0 || priority >= len(c.classes) {
+		panic("prioritycache: priority out of range")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.cfg.Now()
+	if old, ok := c.items[key]; ok {
+		c.remove(old, Deleted)
+	}
+	for len(c.items) >= c.cfg.Capacity {
+		c.evictOne(now)
+	}
+
+	e := &entry[K, V]{key: key, value: value, priority: priority, heapIdx: -1}
+	if ttl > 0 {
+		e.expires = now.Add(ttl)
+		heap.Push(&c.expiry, e)
+	}
+	e.elem = c.classes[priority].PushFront(e)
+	c.items[key] = e
+	c.record("inserts")
+}
+
+// Delete removes key and reports whether it was present.
+func (c *Cache[K, V]) Delete(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if ok {
+		c.remove(e, Deleted)
+	}
+	return ok
+}
+
+// RemoveExpired drops every expired entry and returns how many were
+// removed.
+func (c *Cache[K, V]) RemoveExpired() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.cfg.Now()
+	n := 0
+	for len(c.expiry) > 0 && c.expired(c.expiry[0], now) {
+		c.remove(c.expiry[0], Expired)
+		n++
+	}
+	return n
+}
+
+// Purge removes every entry without invoking OnEvict. Counters are kept.
+func (c *Cache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.items)
+	for _, l := range c.classes {
+		l.Init()
+	}
+	c.expiry = nil
+}
+
+func (c *Cache[K, V]) expired(e *entry[K, V], now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+func (c *Cache[K, V]) evictOne(now time.Time) {
+	if len(c.expiry) > 0 && c.expired(c.expiry[0], now) {
+		c.remove(c.expiry[0], Expired)
+		return
+	}
+	for _, l := range c.classes {
+		if back := l.Back(); back != nil {
+			c.remove(back.Value.(*entry[K, V]), Evicted)
+			return
+		}
+	}
+}
+
+func (c *Cache[K, V]) remove(e *entry[K, V], reason Reason) {
+	delete(c.items, e.key)
+	c.classes[e.priority].Remove(e.elem)
+	if e.heapIdx >= 0 {
+		heap.Remove(&c.expiry, e.heapIdx)
+	}
+	switch reason {
+	case Expired:
+		c.stats.Expirations++
+		c.record("expirations")
+	case Evicted:
+		c.stats.Evictions++
+		c.record("evictions")
+	}
+	if c.cfg.OnEvict != nil {
+		c.cfg.OnEvict(e.key, e.value, reason)
+	}
+}
+
+func (c *Cache[K, V]) record(name string) {
+	if c.cfg.Recorder != nil {
+		c.cfg.Recorder.Add(name, 1)
+	}
+}
@@ -0,0 +1,261 @@
+package prioritycache
+
+import (
+	"container/list"
+	"math/rand"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/anon-org/ds/metrics"
+)
+
+type modelEntry struct {
+	value    int
+	priority int
+	expires  time.Time
+	used     int
+}
+
+// model is a brute-force cache that scans every entry to pick a victim.
+type model struct {
+	capacity int
+	items    map[int]*modelEntry
+	clock    int
+	stats    Stats
+	evicted  []Reason
+}
+
+func (m *model) expired(e *modelEntry, now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+func (m *model) remove(k int, reason Reason) {
+	delete(m.items, k)
+	switch reason {
+	case Expired:
+		m.stats.Expirations++
+	case Evicted:
+		m.stats.Evictions++
+	}
+	m.evicted = append(m.evicted, reason)
+}
+
+func (m *model) victim(now time.Time) (int, Reason) {
+	best, reason := -1, Evicted
+	for k, e := range m.items {
+		if m.expired(e, now) && (reason != Expired || e.expires.Before(m.items[best].expires)) {
+			best, reason = k, Expired
+		}
+	}
+	if reason == Expired {
+		return best, reason
+	}
+	for k, e := range m.items {
+		if b := m.items[best]; best < 0 || e.priority < b.priority || e.priority == b.priority && e.used < b.used {
+			best = k
+		}
+	}
+	return best, Evicted
+}
+
+func (m *model) set(k, v int, ttl time.Duration, priority int, now time.Time) {
+	if _, ok := m.items[k]; ok {
+		m.remove(k, Deleted)
+	}
+	for len(m.items) >= m.capacity {
+		m.remove(m.victim(now))
+	}
+	m.clock++
+	e := &modelEntry{value: v, priority: priority, used: m.clock}
+	if ttl > 0 {
+		e.expires = now.Add(ttl)
+	}
+	m.items[k] = e
+}
+
+func (m *model) get(k int, now time.Time) (int, bool) {
+	e, ok := m.items[k]
+	if ok && m.expired(e, now) {
+		m.remove(k, Expired)
+		ok = false
+	}
+	if !ok {
+		m.stats.Misses++
+		return 0, false
+	}
+	m.stats.Hits++
+	m.clock++
+	e.used = m.clock
+	return e.value, true
+}
+
+func TestRandomAgainstModel(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	base := time.Unix(0, 0)
+	now := base
+	var got []Reason
+	c := New(Config[int, int]{
+		Capacity:   8,
+		Priorities: 3,
+		OnEvict:    func(_, _ int, reason Reason) { got = append(got, reason) },
+		Now:        func() time.Time { return now },
+	})
+	m := &model{capacity: 8, items: make(map[int]*modelEntry)}
+	for step := 0; step < 20000; step++ {
+		now = base.Add(time.Duration(step) * time.Millisecond)
+		k := r.Intn(20)
+		switch op := r.Intn(10); {
+		case op < 4:
+			// Offset every TTL by the step so expiry times never tie.
+			var ttl time.Duration
+			if r.Intn(2) == 0 {
+				ttl = time.Duration(r.Intn(50))*time.Millisecond + time.Duration(step+1)
+			}
+			p := r.Intn(3)
+			c.SetWithOptions(k, step, ttl, p)
+			m.set(k, step, ttl, p, now)
+		case op < 8:
+			v, ok := c.Get(k)
+			wv, wok := m.get(k, now)
+			if v != wv || ok != wok {
+				t.Fatalf("step %d: Get(%d) = %d, %v; want %d, %v", step, k, v, ok, wv, wok)
+			}
+		case op < 9:
+			_, wok := m.items[k]
+			if ok := c.Delete(k); ok != wok {
+				t.Fatalf("step %d: Delete(%d) = %v, want %v", step, k, ok, wok)
+			}
+			if wok {
+				m.remove(k, Deleted)
+			}
+		default:
+			want := 0
+			for k, e := range m.items {
+				if m.expired(e, now) {
+					m.remove(k, Expired)
+					want++
+				}
+			}
+			if n := c.RemoveExpired(); n != want {
+				t.Fatalf("step %d: RemoveExpired() = %d, want %d", step, n, want)
+			}
+		}
+		if c.Len() != len(m.items) {
+			t.Fatalf("step %d: Len() = %d, want %d", step, c.Len(), len(m.items))
+		}
+		if c.Stats() != m.stats {
+			t.Fatalf("step %d: Stats() = %+v, want %+v", step, c.Stats(), m.stats)
+		}
+		if len(got) != len(m.evicted) {
+			t.Fatalf("step %d: %d OnEvict calls, want %d", step, len(got), len(m.evicted))
+		}
+	}
+	// RemoveExpired and the model may visit expired entries in different
+	// orders, so compare how often each reason was reported.
+	counts := func(rs []Reason) [3]int {
+		var n [3]int
+		for _, r := range rs {
+			n[r]++
+		}
+		return n
+	}
+	if counts(got) != counts(m.evicted) {
+		t.Fatalf("OnEvict reasons %v, want %v", counts(got), counts(m.evicted))
+	}
+}
+
+func TestPeekAndPurge(t *testing.T) {
+	now := time.Unix(0, 0)
+	calls := 0
+	c := New(Config[string, int]{
+		Capacity:   2,
+		DefaultTTL: time.Second,
+		OnEvict:    func(string, int, Reason) { calls++ },
+		Now:        func() time.Time { return now },
+	})
+	c.Set("a", 1)
+	if v, ok := c.Peek("a"); !ok || v != 1 {
+		t.Fatalf("Peek(a) = %d, %v", v, ok)
+	}
+	if s := c.Stats(); s.Hits+s.Misses != 0 {
+		t.Fatalf("Peek changed stats: %+v", s)
+	}
+	now = now.Add(time.Second)
+	if _, ok := c.Peek("a"); ok {
+		t.Fatal("Peek returned an expired entry")
+	}
+	c.Purge()
+	if c.Len() != 0 || calls != 0 {
+		t.Fatalf("Purge left %d entries, %d callbacks", c.Len(), calls)
+	}
+	if got := (Stats{Hits: 3, Misses: 1}).HitRate(); got != 0.75 {
+		t.Fatalf("HitRate() = %v, want 0.75", got)
+	}
+}
+
+func TestNewPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("New with zero capacity did not panic")
+		}
+	}()
+	New(Config[int, int]{})
+}
+
+func TestRecorder(t *testing.T) {
+	var rec metrics.Counters
+	now := time.Unix(0, 0)
+	c := New(Config[int, int]{
+		Capacity:   4,
+		DefaultTTL: 10 * time.Millisecond,
+		Now:        func() time.Time { return now },
+		Recorder:   &rec,
+	})
+	r := rand.New(rand.NewSource(5))
+	inserts := 0
+	for i := 0; i < 2000; i++ {
+		now = now.Add(time.Millisecond)
+		if r.Intn(2) == 0 {
+			c.Set(r.Intn(10), i)
+			inserts++
+		} else {
+			c.Get(r.Intn(10))
+		}
+	}
+	s := c.Stats()
+	for name, want := range map[string]uint64{
+		"hits":        s.Hits,
+		"misses":      s.Misses,
+		"evictions":   s.Evictions,
+		"expirations": s.Expirations,
+		"inserts":     uint64(inserts),
+	} {
+		if got := rec.Get(name); got != int64(want) || want == 0 {
+			t.Errorf("%s = %d, want %d (nonzero)", name, got, want)
+		}
+	}
+}
+
+func TestSizeof(t *testing.T) {
+	c := New(Config[int, int]{Capacity: 100, Priorities: 3})
+	empty := c.Sizeof()
+	perEntry := int(unsafe.Sizeof(entry[int, int]{}) + unsafe.Sizeof(list.Element{}))
+	for i := range 60 {
+		c.SetWithOptions(i, i, 0, i%3)
+	}
+	if got, want := c.Sizeof(), empty+60*perEntry; got != want {
+		t.Fatalf("Sizeof() = %d with 60 entries, want %d", got, want)
+	}
+	// Replacing keys and evicting at capacity keep one entry per slot.
+	for i := range 200 {
+		c.SetWithOptions(i%150, i, 0, i%3)
+	}
+	if got, want := c.Sizeof(), empty+c.Len()*perEntry; c.Len() != 100 || got != want {
+		t.Fatalf("Sizeof() = %d with %d entries, want %d", got, c.Len(), want)
+	}
+	c.Purge()
+	if got := c.Sizeof(); got != empty {
+		t.Fatalf("Sizeof() = %d after Purge, want %d", got, empty)
+	}
+}
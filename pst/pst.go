@@ -0,0 +1,135 @@
+// Package pst implements a static priority search tree for three-sided
+// range queries.
+//
+// A priority search tree is a heap on y and a balanced search tree on x at
+// the same time: every node holds the point with the smallest y among its
+// subtree, and the remaining points are split at the median x. Reporting
+// all points with x in [x1, x2] and y ≤ y2 then takes O(log n + k) for k
+// results, which suits scheduling and skyline problems where one side of
+// the query rectangle is unbounded.
+package pst
+
+import (
+	"cmp"
+	"slices"
+	"unsafe"
+)
+
+// Point is a point with a payload.
+type Point[K cmp.Ordered, V any] struct {
+	X, Y  K
+	Value V
+}
+
+type node[K cmp.Ordered, V any] struct {
+	point       Point[K, V]
+	split       K // points with X ≤ split go left, X ≥ split go right
+	left, right *node[K, V]
+}
+
+// Tree is an immutable priority search tree.
+type Tree[K cmp.Ordered, V any] struct {
+	root *node[K, V]
+	size int
+}
+
+// New builds a tree over points in O(n log n). The slice is not retained.
+func New[K cmp.Ordered, V any](points []Point[K, V]) *Tree[K, V] {
+	sorted := slices.Clone(points)
+	slices.SortFunc(sorted, func(a, b Point[K, V]) int { return cmp.Compare(a.X, b.X) })
+	return &Tree[K, V]{root: build(sorted), size: len(points)}
+}
+
+// build constructs the subtree for pts, which is sorted by X and may be
+// reordered.
+func build[K cmp.Ordered, V any](pts []Point[K, V]) *node[K, V] {
+	if len(pts) == 0 {
+		return nil
+	}
+	top := 0
+	for i := range pts {
+		if pts[i].Y < pts[top].Y {
+			top = i
+		}
+	}
+	n := &node[K, V]{point: pts[top]}
+	// Remove the top point in place while keeping the rest sorted by X.
+	copy(pts[top:], pts[top+1:])
+	rest := pts[:len(pts)-1]
+	if len(rest) == 0 {
+		return n
+	}
+	mid := (len(rest) + 1) / 2
+	n.split = rest[mid-1].X
+	n.left = build(rest[:mid])
+	n.right = build(rest[mid:])
+	return n
+}
+
+// Len returns the number of points.
+func (t *Tree[K, V]) Len() int {
+	return t.size
+}
+
+// Sizeof returns the approximate heap usage of the tree in bytes: the tree
+// header plus one node per point.
+func (t *Tree[K, V]) Sizeof() int {
+	return int(unsafe.Sizeof(*t)) + t.size*int(unsafe.Sizeof(node[K, V]{}))
+}
+
+// Query calls fn for every point with x1 ≤ X ≤ x2 and Y ≤ y2, in no
+// particular order, until fn returns false.
+func (t *Tree[K, V]) Query(x1, x2, y2 K, fn func(Point[K, V]) bool) {
+	query(t.root, x1, x2, y2, fn)
+}
+
+func query[K cmp.Ordered, V any](n *node[K, V], x1, x2, y2 K, fn func(Point[K, V]) bool) bool {
+	if n == nil || n.point.Y > y2 {
+		return true
+	}
+	if p := n.point; x1 <= p.X && p.X <= x2 && !fn(p) {
+		return false
+	}
+	if x1 <= n.split && !query(n.left, x1, x2, y2, fn) {
+		return false
+	}
+	if x2 >= n.split && !query(n.right, x1, x2, y2, fn) {
+		return false
+	}
+	return true
+}
+
+// Collect returns every point with x1 ≤ X ≤ x2 and Y ≤ y2.
+func (t *Tree[K, V]) Collect(x1, x2, y2 K) []Point[K, V] {
+	var out []Point[K, V]
+	t.Query(x1, x2, y2, func(p Point[K, V]) bool {
+		out = append(out, p)
+		return true
+	})
+	return out
+}
+
+// MinY returns the point with the smallest Y among those with
+// x1 ≤ X ≤ x2. The boolean is false if there is none.
+func (t *Tree[K, V]) MinY(x1, x2 K) (Point[K, V], bool) {
+	var best Point[K, V]
+	found := false
+	var walk func(n *node[K, V])
+	walk = func(n *node[K, V]) {
+		if n == nil || (found && n.point.Y >= best.Y) {
+			return
+		}
+		if p := n.point; x1 <= p.X && p.X <= x2 {
+			best, found = p, true
+			return
+		}
+		if x1 <= n.split {
+			walk(n.left)
+		}
+		if x2 >= n.split {
+			walk(n.right)
+		}
+	}
+	walk(t.root)
+	return best, found
+}
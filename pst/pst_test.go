@@ -0,0 +1,113 @@
+package pst
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+	"unsafe"
+)
+
+func TestAgainstScan(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 100; trial++ {
+		pts := make([]Point[int, int], r.Intn(80))
+		for i := range pts {
+			// Small coordinate ranges force plenty of ties in X and Y.
+			pts[i] = Point[int, int]{X: r.Intn(20), Y: r.Intn(20), Value: i}
+		}
+		tr := New(pts)
+		if tr.Len() != len(pts) {
+			t.Fatalf("Len() = %d, want %d", tr.Len(), len(pts))
+		}
+		for q := 0; q < 50; q++ {
+			x1, x2, y2 := r.Intn(22)-1, r.Intn(22)-1, r.Intn(22)-1
+			var want []int
+			minY, found := 0, false
+			for _, p := range pts {
+				if x1 <= p.X && p.X <= x2 {
+					if p.Y <= y2 {
+						want = append(want, p.Value)
+					}
+					if !found || p.Y < minY {
+						minY, found = p.Y, true
+					}
+				}
+			}
+			var got []int
+			for _, p := range tr.Collect(x1, x2, y2) {
+				got = append(got, p.Value)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, want) {
+				t.Fatalf("Collect(%d, %d, %d) = %v, want %v", x1, x2, y2, got, want)
+			}
+			p, ok := tr.MinY(x1, x2)
+			if ok != found || (ok && (p.Y != minY || p.X < x1 || p.X > x2)) {
+				t.Fatalf("MinY(%d, %d) = %+v, %v; want Y %d, %v", x1, x2, p, ok, minY, found)
+			}
+		}
+	}
+}
+
+func TestQueryStopsEarly(t *testing.T) {
+	pts := make([]Point[int, struct{}], 100)
+	for i := range pts {
+		pts[i] = Point[int, struct{}]{X: i, Y: i % 7}
+	}
+	calls := 0
+	New(pts).Query(0, 99, 10, func(Point[int, struct{}]) bool {
+		calls++
+		return calls < 3
+	})
+	if calls != 3 {
+		t.Fatalf("Query made %d calls after stopping, want 3", calls)
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	tr := New[float64, string](nil)
+	if got := tr.Collect(-1, 1, 1); got != nil {
+		t.Fatalf("Collect on empty tree = %v", got)
+	}
+	if _, ok := tr.MinY(-1, 1); ok {
+		t.Fatal("MinY on empty tree found a point")
+	}
+}
+
+func TestNewAllocations(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	pts := make([]Point[int, int], 500)
+	for i := range pts {
+		pts[i] = Point[int, int]{X: r.Intn(1000), Y: r.Intn(1000)}
+	}
+	// One node per point, plus the sorted copy and the tree header.
+	if got := testing.AllocsPerRun(10, func() { New(pts) }); got != float64(len(pts)+2) {
+		t.Fatalf("New made %v allocations, want %d", got, len(pts)+2)
+	}
+}
+
+func TestSizeof(t *testing.T) {
+	r := rand.New(rand.NewSource(9))
+	empty := New[int, int](nil).Sizeof()
+	nodeSize := int(unsafe.Sizeof(node[int, int]{}))
+	for _, n := range []int{1, 2, 7, 100, 1000} {
+		pts := make([]Point[int, int], n)
+		for i := range pts {
+			pts[i] = Point[int, int]{X: r.Intn(50), Y: r.Intn(50)}
+		}
+		tr := New(pts)
+		var nodes func(*node[int, int]) int
+		nodes = func(x *node[int, int]) int {
+			if x == nil {
+				return 0
+			}
+			return 1 + nodes(x.left) + nodes(x.right)
+		}
+		if got := nodes(tr.root); got != n {
+			t.Fatalf("n=%d: tree has %d nodes", n, got)
+		}
+		if got, want := tr.Sizeof(), empty+n*nodeSize; got != want {
+			t.Fatalf("n=%d: Sizeof() = %d, want %d", n, got, want)
+		}
+	}
+}
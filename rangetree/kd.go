@@ -0,0 +1,215 @@
+package rangetree
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"sort"
+	"unsafe"
+)
+
+// ErrDimension is returned by NewKD when points have differing or zero
+// dimensions.
+var ErrDimension = errors.New("rangetree: points have inconsistent dimensions")
+
+// PointKD is a point in k dimensions with a payload.
+type PointKD[K cmp.Ordered, V any] struct {
+	Coords []K
+	Value  V
+}
+
+// TreeKD is an immutable layered range tree over k-dimensional points. The
+// first k-2 dimensions are handled by nested balanced trees and the last two
+// by a Tree2D with fractional cascading.
+type TreeKD[K cmp.Ordered, V any] struct {
+	points []PointKD[K, V]
+	dims   int
+	root   *level[K]
+}
+
+// level indexes a subset of the points, given as indices into
+// TreeKD.points, on coordinate dim and above.
+type level[K cmp.Ordered] struct {
+	dim    int
+	idx    []int32     // subset sorted by Coords[dim]
+	sub    []*level[K] // segment tree over idx, for dim < dims-2
+	planar *Tree2D[K, int32]
+}
+
+// NewKD builds a range tree over points, all of which must have the same
+// positive number of coordinates. The slice is not retained, but the
+// coordinate slices are.
+func NewKD[K cmp.Ordered, V any](points []PointKD[K, V]) (*TreeKD[K, V], error) {
+	t := &TreeKD[K, V]{points: slices.Clone(points)}
+	if len(points) == 0 {
+		return t, nil
+	}
+	t.dims = len(points[0].Coords)
+	for _, p := range points {
+		if len(p.Coords) != t.dims || t.dims == 0 {
+			return nil, ErrDimension
+		}
+	}
+	idx := make([]int32, len(points))
+	for i := range idx {
+		idx[i] = int32(i)
+	}
+	t.root = t.build(0, idx)
+	return t, nil
+}
+
+func (t *TreeKD[K, V]) build(dim int, idx []int32) *level[K] {
+	slices.SortStableFunc(idx, func(a, b int32) int {
+		return cmp.Compare(t.points[a].Coords[dim], t.points[b].Coords[dim])
+	})
+	l := &level[K]{dim: dim, idx: idx}
+	switch {
+	case dim == t.dims-1:
+		// A single remaining dimension needs only the sorted order.
+	case dim == t.dims-2:
+		pts := make([]Point[K, int32], len(idx))
+		for i, id := range idx {
+			c := t.points[id].Coords
+			pts[i] = Point[K, int32]{X: c[dim], Y: c[dim+1], Value: id}
+		}
+		l.planar = New2D(pts)
+	default:
+		l.sub = make([]*level[K], 4*len(idx))
+		t.buildNodes(l, 1, 0, len(idx))
+	}
+	return l
+}
+
+func (t *TreeKD[K, V]) buildNodes(l *level[K], v, lo, hi int) {
+	l.sub[v] = t.build(l.dim+1, slices.Clone(l.idx[lo:hi]))
+	if hi-lo > 1 {
+		mid := (lo + hi) / 2
+		t.buildNodes(l, 2*v, lo, mid)
+		t.buildNodes(l, 2*v+1, mid, hi)
+	}
+}
+
+// Dims returns the number of coordinates per point, or 0 for an empty
+// tree.
+func (t *TreeKD[K, V]) Dims() int {
+	return t.dims
+}
+
+// Len returns the number of points.
+func (t *TreeKD[K, V]) Len() int {
+	return len(t.points)
+}
+
+// Sizeof returns the approximate heap usage of the tree in bytes, nested
+// levels included. The coordinate slices are shared with the caller and
+// left out.
+func (t *TreeKD[K, V]) Sizeof() int {
+	size := int(unsafe.Sizeof(*t)) + cap(t.points)*int(unsafe.Sizeof(PointKD[K, V]{}))
+	if t.root != nil {
+		size += t.root.sizeof()
+	}
+	return size
+}
+
+func (l *level[K]) sizeof() int {
+	size := int(unsafe.Sizeof(*l)) + 4*cap(l.idx) + cap(l.sub)*int(unsafe.Sizeof(l))
+	for _, s := range l.sub {
+		if s != nil {
+			size += s.sizeof()
+		}
+	}
+	if l.planar != nil {
+		size += l.planar.Sizeof()
+	}
+	return size
+}
+
+func (t *TreeKD[K, V]) valid(lo, hi []K) bool {
+	if t.root == nil {
+		return false
+	}
+	if len(lo) != t.dims || len(hi) != t.dims {
+		panic("rangetree: query dimension mismatch")
+	}
+	return true
+}
+
+// Count returns the number of points p with lo[i] ≤ p.Coords[i] ≤ hi[i] for
+// every dimension i.
+func (t *TreeKD[K, V]) Count(lo, hi []K) int {
+	if !t.valid(lo, hi) {
+		return 0
+	}
+	total := 0
+	t.visit(t.root, lo, hi, func(l *level[K], a, b int) bool {
+		total += b - a
+		return true
+	}, func(l *level[K]) bool {
+		total += l.planar.Count(lo[l.dim], hi[l.dim], lo[l.dim+1], hi[l.dim+1])
+		return true
+	})
+	return total
+}
+
+// Query calls fn for every point inside the box [lo, hi] until fn returns
+// false.
+func (t *TreeKD[K, V]) Query(lo, hi []K, fn func(PointKD[K, V]) bool) {
+	if !t.valid(lo, hi) {
+		return
+	}
+	t.visit(t.root, lo, hi, func(l *level[K], a, b int) bool {
+		for _, id := range l.idx[a:b] {
+			if !fn(t.points[id]) {
+				return false
+			}
+		}
+		return true
+	}, func(l *level[K]) bool {
+		ok := true
+		l.planar.Query(lo[l.dim], hi[l.dim], lo[l.dim+1], hi[l.dim+1], func(p Point[K, int32]) bool {
+			ok = fn(t.points[p.Value])
+			return ok
+		})
+		return ok
+	})
+}
+
+// Collect returns every point inside the box [lo, hi].
+func (t *TreeKD[K, V]) Collect(lo, hi []K) []PointKD[K, V] {
+	var out []PointKD[K, V]
+	t.Query(lo, hi, func(p PointKD[K, V]) bool {
+		out = append(out, p)
+		return true
+	})
+	return out
+}
+
+// visit finds the points of l inside the box. Single-dimension levels hand
+// a range of l.idx to linear; two-dimension levels are handed to planar
+// whole.
+func (t *TreeKD[K, V]) visit(l *level[K], lo, hi []K, linear func(l *level[K], a, b int) bool, planar func(l *level[K]) bool) bool {
+	if l.planar != nil {
+		return planar(l)
+	}
+	coord := func(i int) K { return t.points[l.idx[i]].Coords[l.dim] }
+	a := sort.Search(len(l.idx), func(i int) bool { return coord(i) >= lo[l.dim] })
+	b := sort.Search(len(l.idx), func(i int) bool { return coord(i) > hi[l.dim] })
+	if a >= b {
+		return true
+	}
+	if l.sub == nil {
+		return linear(l, a, b)
+	}
+	var descend func(v, nlo, nhi int) bool
+	descend = func(v, nlo, nhi int) bool {
+		if b <= nlo || nhi <= a {
+			return true
+		}
+		if a <= nlo && nhi <= b {
+			return t.visit(l.sub[v], lo, hi, linear, planar)
+		}
+		mid := (nlo + nhi) / 2
+		return descend(2*v, nlo, mid) && descend(2*v+1, mid, nhi)
+	}
+	return descend(1, 0, len(l.idx))
+}
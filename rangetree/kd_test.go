@@ -0,0 +1,140 @@
+package rangetree
+
+import (
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+	"unsafe"
+)
+
+func TestTreeKDAgainstScan(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for _, dims := range []int{1, 2, 3, 4} {
+		for trial := 0; trial < 30; trial++ {
+			pts := make([]PointKD[int, int], r.Intn(60))
+			for i := range pts {
+				c := make([]int, dims)
+				for d := range c {
+					c[d] = r.Intn(8)
+				}
+				pts[i] = PointKD[int, int]{Coords: c, Value: i}
+			}
+			tr, err := NewKD(pts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tr.Len() != len(pts) {
+				t.Fatalf("Len() = %d, want %d", tr.Len(), len(pts))
+			}
+			for q := 0; q < 40; q++ {
+				lo, hi := make([]int, dims), make([]int, dims)
+				for d := range lo {
+					lo[d], hi[d] = r.Intn(10)-1, r.Intn(10)-1
+				}
+				var want []int
+			points:
+				for _, p := range pts {
+					for d, c := range p.Coords {
+						if c < lo[d] || c > hi[d] {
+							continue points
+						}
+					}
+					want = append(want, p.Value)
+				}
+				if len(pts) == 0 {
+					if c := tr.Count(lo, hi); c != 0 {
+						t.Fatalf("Count on empty tree = %d", c)
+					}
+					continue
+				}
+				var got []int
+				for _, p := range tr.Collect(lo, hi) {
+					got = append(got, p.Value)
+				}
+				slices.Sort(got)
+				if !slices.Equal(got, want) {
+					t.Fatalf("dims %d: Collect(%v, %v) = %v, want %v", dims, lo, hi, got, want)
+				}
+				if c := tr.Count(lo, hi); c != len(want) {
+					t.Fatalf("dims %d: Count(%v, %v) = %d, want %d", dims, lo, hi, c, len(want))
+				}
+			}
+		}
+	}
+}
+
+func TestTreeKDInvalid(t *testing.T) {
+	for _, pts := range [][]PointKD[int, int]{
+		{{Coords: []int{1, 2}}, {Coords: []int{1}}},
+		{{Coords: []int{}}},
+	} {
+		if _, err := NewKD(pts); !errors.Is(err, ErrDimension) {
+			t.Errorf("NewKD(%v) error = %v, want ErrDimension", pts, err)
+		}
+	}
+	tr, err := NewKD([]PointKD[int, int]{{Coords: []int{1, 2, 3}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Dims() != 3 {
+		t.Fatalf("Dims() = %d, want 3", tr.Dims())
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("query with wrong dimension did not panic")
+		}
+	}()
+	tr.Count([]int{0}, []int{5})
+}
+
+func TestSizeof(t *testing.T) {
+	const n = 64
+	r := rand.New(rand.NewSource(9))
+	// planar returns the size of a Tree2D over m points, which depends
+	// only on m.
+	planar := func(m int) int {
+		pts := make([]Point[int, int32], m)
+		for i := range pts {
+			pts[i] = Point[int, int32]{X: r.Intn(100), Y: r.Intn(100)}
+		}
+		return New2D(pts).Sizeof()
+	}
+	lvl := int(unsafe.Sizeof(level[int]{}))
+	ptr := int(unsafe.Sizeof(uintptr(0)))
+	// One dimension is a sorted index and two add a planar tree. Three add
+	// a segment tree of 4n slots whose nodes each hold a planar level over
+	// their span: with n a power of two, 2^d nodes of n/2^d points at
+	// every depth d. A one-point index still takes a minimal 8-byte
+	// allocation.
+	seg := lvl + 4*n + 4*n*ptr
+	for m := n; m >= 1; m /= 2 {
+		seg += (n / m) * (lvl + 4*max(m, 2) + planar(m))
+	}
+	levels := map[int]int{
+		1: lvl + 4*n,
+		2: lvl + 4*n + planar(n),
+		3: seg,
+	}
+	for dims := 1; dims <= 3; dims++ {
+		pts := make([]PointKD[int, int], n)
+		for i := range pts {
+			pts[i].Coords = make([]int, dims)
+			for d := range dims {
+				pts[i].Coords[d] = r.Intn(100)
+			}
+		}
+		tr, err := NewKD(pts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := int(unsafe.Sizeof(*tr)) + cap(tr.points)*int(unsafe.Sizeof(pts[0])) + levels[dims]
+		if got := tr.Sizeof(); got != want {
+			t.Fatalf("%d dimensions: Sizeof() = %d, want %d", dims, got, want)
+		}
+	}
+	empty, _ := NewKD[int, int](nil)
+	if got, want := empty.Sizeof(), int(unsafe.Sizeof(*empty)); got != want {
+		t.Fatalf("empty tree: Sizeof() = %d, want %d", got, want)
+	}
+}
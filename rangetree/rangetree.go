@@ -0,0 +1,160 @@
+// Package rangetree implements static layered range trees for orthogonal
+// range counting and reporting.
+//
+// Tree2D is a balanced tree over x whose nodes keep their points sorted by
+// y. Fractional cascading links every node's y-order to its children's, so
+// after one binary search at the root each of the O(log n) canonical nodes
+// is reached in O(1): counting takes O(log n) and reporting O(log n + k).
+// TreeKD stacks further levels on top for k dimensions, answering queries
+// in O(log^(k-1) n + k) with O(n log^(k-1) n) space. Unlike a k-d tree,
+// these bounds hold for every input, not just well-distributed ones.
+package rangetree
+
+import (
+	"cmp"
+	"slices"
+	"sort"
+	"unsafe"
+)
+
+// Point is a 2-D point with a payload.
+type Point[K cmp.Ordered, V any] struct {
+	X, Y  K
+	Value V
+}
+
+// Tree2D is an immutable 2-D range tree.
+type Tree2D[K cmp.Ordered, V any] struct {
+	points []Point[K, V] // sorted by X
+	// Node v of the implicit segment tree over points covers a range of
+	// positions; ys[v] lists those positions ordered by Y, and left[v][i]
+	// counts how many of the first i entries of ys[v] belong to the left
+	// child.
+	ys   [][]int32
+	left [][]int32
+	n    int
+}
+
+// New2D builds a range tree over points in O(n log n). The slice is not
+// retained.
+func New2D[K cmp.Ordered, V any](points []Point[K, V]) *Tree2D[K, V] {
+	t := &Tree2D[K, V]{points: slices.Clone(points), n: len(points)}
+	slices.SortStableFunc(t.points, func(a, b Point[K, V]) int { return cmp.Compare(a.X, b.X) })
+	if t.n == 0 {
+		return t
+	}
+	t.ys = make([][]int32, 4*t.n)
+	t.left = make([][]int32, 4*t.n)
+	t.build(1, 0, t.n)
+	return t
+}
+
+func (t *Tree2D[K, V]) lessY(i, j int32) bool {
+	if c := cmp.Compare(t.points[i].Y, t.points[j].Y); c != 0 {
+		return c < 0
+	}
+	return i < j
+}
+
+func (t *Tree2D[K, V]) build(v, lo, hi int) {
+	if hi-lo == 1 {
+		t.ys[v] = []int32{int32(lo)}
+		return
+	}
+	mid := (lo + hi) / 2
+	t.build(2*v, lo, mid)
+	t.build(2*v+1, mid, hi)
+	l, r := t.ys[2*v], t.ys[2*v+1]
+	ys := make([]int32, 0, len(l)+len(r))
+	left := make([]int32, 1, len(l)+len(r)+1)
+	i, j := 0, 0
+	for i < len(l) || j < len(r) {
+		if j == len(r) || (i < len(l) && t.lessY(l[i], r[j])) {
+			ys = append(ys, l[i])
+			i++
+		} else {
+			ys = append(ys, r[j])
+			j++
+		}
+		left = append(left, int32(i))
+	}
+	t.ys[v], t.left[v] = ys, left
+}
+
+// Len returns the number of points.
+func (t *Tree2D[K, V]) Len() int {
+	return t.n
+}
+
+// Sizeof returns the approximate heap usage of the tree in bytes, including
+// the Y order and child counts kept at every node.
+func (t *Tree2D[K, V]) Sizeof() int {
+	size := int(unsafe.Sizeof(*t)) + cap(t.points)*int(unsafe.Sizeof(Point[K, V]{})) +
+		(cap(t.ys)+cap(t.left))*int(unsafe.Sizeof([]int32(nil)))
+	for v := range t.ys {
+		size += 4 * (cap(t.ys[v]) + cap(t.left[v]))
+	}
+	return size
+}
+
+// visit calls fn with every canonical node covering [x1, x2] together with
+// the range [p, q) of its ys entries whose Y lies in [y1, y2].
+func (t *Tree2D[K, V]) visit(x1, x2, y1, y2 K, fn func(v, p, q int) bool) {
+	if t.n == 0 || x1 > x2 || y1 > y2 {
+		return
+	}
+	lo := sort.Search(t.n, func(i int) bool { return t.points[i].X >= x1 })
+	hi := sort.Search(t.n, func(i int) bool { return t.points[i].X > x2 })
+	root := t.ys[1]
+	p := sort.Search(len(root), func(i int) bool { return t.points[root[i]].Y >= y1 })
+	q := sort.Search(len(root), func(i int) bool { return t.points[root[i]].Y > y2 })
+	t.descend(1, 0, t.n, lo, hi, p, q, fn)
+}
+
+func (t *Tree2D[K, V]) descend(v, lo, hi, qlo, qhi, p, q int, fn func(v, p, q int) bool) bool {
+	if qhi <= lo || hi <= qlo || p >= q {
+		return true
+	}
+	if qlo <= lo && hi <= qhi {
+		return fn(v, p, q)
+	}
+	mid := (lo + hi) / 2
+	left := t.left[v]
+	lp, lq := int(left[p]), int(left[q])
+	return t.descend(2*v, lo, mid, qlo, qhi, lp, lq, fn) &&
+		t.descend(2*v+1, mid, hi, qlo, qhi, p-lp, q-lq, fn)
+}
+
+// Count returns the number of points with x1 ≤ X ≤ x2 and y1 ≤ Y ≤ y2.
+func (t *Tree2D[K, V]) Count(x1, x2, y1, y2 K) int {
+	total := 0
+	t.visit(x1, x2, y1, y2, func(_, p, q int) bool {
+		total += q - p
+		return true
+	})
+	return total
+}
+
+// Query calls fn for every point with x1 ≤ X ≤ x2 and y1 ≤ Y ≤ y2 until fn
+// returns false. Points are grouped by canonical node and ordered by Y
+// within each group.
+func (t *Tree2D[K, V]) Query(x1, x2, y1, y2 K, fn func(Point[K, V]) bool) {
+	t.visit(x1, x2, y1, y2, func(v, p, q int) bool {
+		for _, i := range t.ys[v][p:q] {
+			if !fn(t.points[i]) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Collect returns every point with x1 ≤ X ≤ x2 and y1 ≤ Y ≤ y2.
+func (t *Tree2D[K, V]) Collect(x1, x2, y1, y2 K) []Point[K, V] {
+	var out []Point[K, V]
+	t.Query(x1, x2, y1, y2, func(p Point[K, V]) bool {
+		out = append(out, p)
+		return true
+	})
+	return out
+}
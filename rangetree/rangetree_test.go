@@ -0,0 +1,42 @@
+package rangetree
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestTree2DAgainstScan(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 100; trial++ {
+		pts := make([]Point[int, int], r.Intn(100))
+		for i := range pts {
+			pts[i] = Point[int, int]{X: r.Intn(25), Y: r.Intn(25), Value: i}
+		}
+		tr := New2D(pts)
+		if tr.Len() != len(pts) {
+			t.Fatalf("Len() = %d, want %d", tr.Len(), len(pts))
+		}
+		for q := 0; q < 50; q++ {
+			x1, x2 := r.Intn(27)-1, r.Intn(27)-1
+			y1, y2 := r.Intn(27)-1, r.Intn(27)-1
+			var want []int
+			for _, p := range pts {
+				if x1 <= p.X && p.X <= x2 && y1 <= p.Y && p.Y <= y2 {
+					want = append(want, p.Value)
+				}
+			}
+			var got []int
+			for _, p := range tr.Collect(x1, x2, y1, y2) {
+				got = append(got, p.Value)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, want) {
+				t.Fatalf("Collect(%d, %d, %d, %d) = %v, want %v", x1, x2, y1, y2, got, want)
+			}
+			if c := tr.Count(x1, x2, y1, y2); c != len(want) {
+				t.Fatalf("Count(%d, %d, %d, %d) = %d, want %d", x1, x2, y1, y2, c, len(want))
+			}
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// Package rle implements a run-length encoded sequence.
+//
+// A Sequence stores each maximal run of equal elements once together with
+// its end offset, so memory is proportional to the number of runs rather
+// than the number of elements. Random access binary-searches the run
+// offsets in O(log r), which keeps long, highly repetitive sequences such
+// as genotype or label tracks both compact and indexable.
+package rle
+
+import (
+	"iter"
+	"slices"
+	"sort"
+	"unsafe"
+)
+
+// Sequence is a run-length encoded sequence of comparable values. The zero
+// value is an empty sequence ready to use.
+type Sequence[T comparable] struct {
+	values []T
+	ends   []int // ends[r] is the exclusive end offset of run r
+}
+
+// FromSlice encodes s.
+func FromSlice[T comparable](s []T) *Sequence[T] {
+	seq := &Sequence[T]{}
+	for _, v := range s {
+		seq.Append(v)
+	}
+	return seq
+}
+
+// Len returns the number of elements.
+func (s *Sequence[T]) Len() int {
+	if len(s.ends) == 0 {
+		return 0
+	}
+	return s.ends[len(s.ends)-1]
+}
+
+// NumRuns returns the number of runs.
+func (s *Sequence[T]) NumRuns() int {
+	return len(s.values)
+}
+
+// Sizeof returns the approximate heap usage of the sequence in bytes,
+// including capacity set aside by Reserve.
+func (s *Sequence[T]) Sizeof() int {
+	var zero T
+	return int(unsafe.Sizeof(*s)) + cap(s.values)*int(unsafe.Sizeof(zero)) +
+		cap(s.ends)*int(unsafe.Sizeof(0))
+}
+
+// Reserve grows the sequence's storage, if necessary, so that n more runs
+// can be appended without another allocation.
+func (s *Sequence[T]) Reserve(n int) {
+	if n < 0 {
+		panic("rle: negative reserve")
+	}
+	s.values = slices.Grow(s.values, n)
+	s.ends = slices.Grow(s.ends, n)
+}
+
+// ShrinkToFit releases the spare capacity left behind by growing the
+// sequence one run at a time, which can approach the size of the runs
+// themselves.
+func (s *Sequence[T]) ShrinkToFit() {
+	s.values = slices.Clip(slices.Clone(s.values))
+	s.ends = slices.Clip(slices.Clone(s.ends))
+}
+
+// Append adds v to the end of the sequence.
+func (s *Sequence[T]) Append(v T) {
+	s.AppendRun(v, 1)
+}
+
+// AppendRun adds count copies of v to the end of the sequence, extending
+// the last run when it holds v.
+func (s *Sequence[T]) AppendRun(v T, count int) {
+	if count < 0 {
+		panic("rle: negative run length")
+	}
+	if count == 0 {
+		return
+	}
+	if r := len(s.values) - 1; r >= 0 && s.values[r] == v {
+		s.ends[r] += count
+		return
+	}
+	s.values = append(s.values, v)
+	s.ends = append(s.ends, s.Len()+count)
+}
+
+// run returns the index of the run containing element i.
+func (s *Sequence[T]) run(i int) int {
+	if i < 0 || i >= s.Len() {
+		panic("rle: index out of range")
+	}
+	return sort.SearchInts(s.ends, i+1)
+}
+
+// Get returns element i.
+func (s *Sequence[T]) Get(i int) T {
+	return s.values[s.run(i)]
+}
+
+// Slice returns a new sequence holding elements [lo, hi).
+func (s *Sequence[T]) Slice(lo, hi int) *Sequence[T] {
+	if lo < 0 || hi > s.Len() || lo > hi {
+		panic("rle: invalid range")
+	}
+	out := &Sequence[T]{}
+	if lo == hi {
+		return out
+	}
+	for r, pos := s.run(lo), lo; pos < hi; r++ {
+		end := min(s.ends[r], hi)
+		out.AppendRun(s.values[r], end-pos)
+		pos = end
+	}
+	return out
+}
+
+// Concat returns a new sequence holding the elements of s followed by
+// those of other. Adjacent equal runs at the seam are merged.
+func (s *Sequence[T]) Concat(other *Sequence[T]) *Sequence[T] {
+	out := &Sequence[T]{
+		values: make([]T, len(s.values), len(s.values)+len(other.values)),
+		ends:   make([]int, len(s.ends), len(s.ends)+len(other.ends)),
+	}
+	copy(out.values, s.values)
+	copy(out.ends, s.ends)
+	for v, count := range other.Runs() {
+		out.AppendRun(v, count)
+	}
+	return out
+}
+
+// Runs yields every run as its value and length, in order.
+func (s *Sequence[T]) Runs() iter.Seq2[T, int] {
+	return func(yield func(T, int) bool) {
+		start := 0
+		for r, v := range s.values {
+			if !yield(v, s.ends[r]-start) {
+				return
+			}
+			start = s.ends[r]
+		}
+	}
+}
+
+// All yields every element in order.
+func (s *Sequence[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v, count := range s.Runs() {
+			for range count {
+				if !yield(v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Expand decodes the sequence into a slice.
+func (s *Sequence[T]) Expand() []T {
+	out := make([]T, 0, s.Len())
+	for v, count := range s.Runs() {
+		for range count {
+			out = append(out, v)
+		}
+	}
+	return out
+}
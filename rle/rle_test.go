@@ -0,0 +1,125 @@
+package rle
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+	"unsafe"
+)
+
+// checkRuns verifies that s decodes to want and that its runs are maximal.
+func checkRuns(t *testing.T, s *Sequence[int], want []int) {
+	t.Helper()
+	if got := s.Expand(); !slices.Equal(got, want) {
+		t.Fatalf("Expand() = %v, want %v", got, want)
+	}
+	if got := slices.Collect(s.All()); !slices.Equal(got, want) {
+		t.Fatalf("All() = %v, want %v", got, want)
+	}
+	if s.Len() != len(want) {
+		t.Fatalf("Len() = %d, want %d", s.Len(), len(want))
+	}
+	runs := 0
+	for i := range want {
+		if i == 0 || want[i] != want[i-1] {
+			runs++
+		}
+		if got := s.Get(i); got != want[i] {
+			t.Fatalf("Get(%d) = %d, want %d", i, got, want[i])
+		}
+	}
+	if s.NumRuns() != runs {
+		t.Fatalf("NumRuns() = %d, want %d", s.NumRuns(), runs)
+	}
+}
+
+func randomSlice(r *rand.Rand, n int) []int {
+	s := make([]int, n)
+	for i := range s {
+		if i > 0 && r.Intn(4) != 0 {
+			s[i] = s[i-1]
+		} else {
+			s[i] = r.Intn(3)
+		}
+	}
+	return s
+}
+
+func TestAgainstSlice(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 300; trial++ {
+		want := randomSlice(r, r.Intn(60))
+		s := FromSlice(want)
+		checkRuns(t, s, want)
+
+		lo := r.Intn(len(want) + 1)
+		hi := lo + r.Intn(len(want)+1-lo)
+		checkRuns(t, s.Slice(lo, hi), want[lo:hi])
+
+		other := randomSlice(r, r.Intn(20))
+		checkRuns(t, s.Concat(FromSlice(other)), slices.Concat(want, other))
+		checkRuns(t, s, want)
+
+		v, count := r.Intn(3), r.Intn(5)
+		s.AppendRun(v, count)
+		for range count {
+			want = append(want, v)
+		}
+		checkRuns(t, s, want)
+	}
+}
+
+func TestZeroValueAndPanics(t *testing.T) {
+	var s Sequence[int]
+	checkRuns(t, &s, nil)
+	s.Append(7)
+	checkRuns(t, &s, []int{7})
+
+	for name, fn := range map[string]func(){
+		"Get":       func() { s.Get(1) },
+		"Slice":     func() { s.Slice(1, 0) },
+		"AppendRun": func() { s.AppendRun(1, -1) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s did not panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
+
+func TestReserveAndShrinkToFit(t *testing.T) {
+	s := FromSlice([]int{1, 1, 2})
+	s.Reserve(100)
+	values, ends := cap(s.values), cap(s.ends)
+	for i := range 100 {
+		s.Append(i)
+	}
+	if cap(s.values) != values || cap(s.ends) != ends {
+		t.Fatalf("appending reserved runs reallocated")
+	}
+	want := s.Expand()
+	s.ShrinkToFit()
+	if cap(s.values) != s.NumRuns() || cap(s.ends) != s.NumRuns() {
+		t.Fatalf("ShrinkToFit left capacity %d, %d for %d runs", cap(s.values), cap(s.ends), s.NumRuns())
+	}
+	checkRuns(t, s, want)
+}
+
+func TestSizeof(t *testing.T) {
+	s := FromSlice(randomSlice(rand.New(rand.NewSource(9)), 5000))
+	s.ShrinkToFit()
+	// Each run costs one value and one end offset.
+	perRun := int(unsafe.Sizeof(0)) * 2
+	want := int(unsafe.Sizeof(*s)) + s.NumRuns()*perRun
+	if got := s.Sizeof(); got != want {
+		t.Fatalf("Sizeof() = %d for %d runs, want %d", got, s.NumRuns(), want)
+	}
+	s.Reserve(100)
+	if got := s.Sizeof(); got < want+100*perRun {
+		t.Fatalf("Sizeof() = %d after Reserve(100), want at least %d", got, want+100*perRun)
+	}
+}
@@ -0,0 +1,310 @@
+// Package scapegoat implements a scapegoat tree, a self-balancing binary
+// search tree that stores no balance metadata in its nodes. Instead of
+// rotating on every update it rebuilds the smallest unbalanced subtree when
+// an insertion lands too deep, giving O(log n) amortized updates and
+// O(log n) worst-case lookups.
+//
+// A Tree given a metrics.Recorder reports the counters "inserts",
+// "deletes", "insert_depth", "rebuilds" and "rebuilt_nodes". insert_depth
+// sums the depth at which each new key landed, so insert_depth/inserts is
+// the mean search depth the balancing keeps logarithmic; rebuilt_nodes is
+// the total size of rebuilt subtrees, the amortized cost of doing so.
+package scapegoat
+
+import (
+	"cmp"
+	"math"
+	"unsafe"
+
+	"github.com/anon-org/ds/metrics"
+)
+
+// DefaultAlpha is the weight-balance factor used by New.
+const DefaultAlpha = 0.7
+
+type node[K cmp.Ordered, V any] struct {
+	key   K
+	value V
+	left  *node[K, V]
+	right *node[K, V]
+}
+
+// Tree is an ordered map backed by a scapegoat tree. The zero value is not
+// usable; create trees with New or NewWithAlpha.
+type Tree[K cmp.Ordered, V any] struct {
+	root    *node[K, V]
+	size    int
+	maxSize int
+	alpha   float64
+	logInv  float64
+	rec     metrics.Recorder
+}
+
+// New returns an empty tree using DefaultAlpha.
+func New[K cmp.Ordered, V any]() *Tree[K, V] {
+	return NewWithAlpha[K, V](DefaultAlpha)
+}
+
+// NewWithAlpha returns an empty tree with the given balance factor. Alpha
+// must lie in (0.5, 1); smaller values keep the tree shallower at the cost
+// of more frequent rebuilds. Out-of-range values fall back to DefaultAlpha.
+func NewWithAlpha[K cmp.Ordered, V any](alpha float64) *Tree[K, V] {
+	if alpha <= 0.5 || alpha >= 1 {
+		alpha = DefaultAlpha
+	}
+	return &Tree[K, V]{alpha: alpha, logInv: math.Log(1 / alpha)}
+}
+
+// SetRecorder directs the tree's counters to r. A nil r disables them.
+func (t *Tree[K, V]) SetRecorder(r metrics.Recorder) {
+	t.rec = r
+}
+
+func (t *Tree[K, V]) record(name string, delta int) {
+	if t.rec != nil {
+		t.rec.Add(name, int64(delta))
+	}
+}
+
+// Len returns the number of keys stored in the tree.
+func (t *Tree[K, V]) Len() int {
+	return t.size
+}
+
+// Sizeof returns the approximate heap usage of the tree in bytes: the tree
+// header plus one node per key. Rebuilds reuse nodes, so it tracks Len.
+func (t *Tree[K, V]) Sizeof() int {
+	return int(unsafe.Sizeof(*t)) + t.size*int(unsafe.Sizeof(node[K, V]{}))
+}
+
+// Get returns the value stored under key and whether it was present.
+func (t *Tree[K, V]) Get(key K) (V, bool) {
+	n := t.root
+	for n != nil {
+		switch c := cmp.Compare(key, n.key); {
+		case c < 0:
+			n = n.left
+		case c > 0:
+			n = n.right
+		default:
+			return n.value, true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+// Contains reports whether key is present in the tree.
+func (t *Tree[K, V]) Contains(key K) bool {
+	_, ok := t.Get(key)
+	return ok
+}
+
+// Put inserts or replaces the value stored under key.
+func (t *Tree[K, V]) Put(key K, value V) {
+	if t.root == nil {
+		t.root = &node[K, V]{key: key, value: value}
+		t.size, t.maxSize = 1, 1
+		t.record("inserts", 1)
+		return
+	}
+
+	path := make([]*node[K, V], 0, t.depthLimit()+2)
+	n := t.root
+	for {
+		path = append(path, n)
+		c := cmp.Compare(key, n.key)
+		if c == 0 {
+			n.value = value
+			return
+		}
+		next := &n.left
+		if c > 0 {
+			next = &n.right
+		}
+		if *next == nil {
+			*next = &node[K, V]{key: key, value: value}
+			path = append(path, *next)
+			break
+		}
+		n = *next
+	}
+
+	t.size++
+	t.maxSize = max(t.maxSize, t.size)
+	t.record("inserts", 1)
+	t.record("insert_depth", len(path)-1)
+	if len(path)-1 > t.depthLimit() {
+		t.rebalance(path)
+	}
+}
+
+// Delete removes key from the tree and reports whether it was present.
+func (t *Tree[K, V]) Delete(key K) bool {
+	link := &t.root
+	for *link != nil {
+		n := *link
+		switch c := cmp.Compare(key, n.key); {
+		case c < 0:
+			link = &n.left
+		case c > 0:
+			link = &n.right
+		default:
+			*link = t.unlink(n)
+			t.size--
+			t.record("deletes", 1)
+			if float64(t.size) < t.alpha*float64(t.maxSize) {
+				t.root = build(flatten(t.root, make([]*node[K, V], 0, t.size)))
+				t.maxSize = t.size
+				t.record("rebuilds", 1)
+				t.record("rebuilt_nodes", t.size)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Min returns the smallest key and its value. The boolean is false when the
+// tree is empty.
+func (t *Tree[K, V]) Min() (K, V, bool) {
+	if t.root == nil {
+		var k K
+		var v V
+		return k, v, false
+	}
+	n := t.root
+	for n.left != nil {
+		n = n.left
+	}
+	return n.key, n.value, true
+}
+
+// Max returns the largest key and its value. The boolean is false when the
+// tree is empty.
+func (t *Tree[K, V]) Max() (K, V, bool) {
+	if t.root == nil {
+		var k K
+		var v V
+		return k, v, false
+	}
+	n := t.root
+	for n.right != nil {
+		n = n.right
+	}
+	return n.key, n.value, true
+}
+
+// Ascend calls fn for every key in ascending order until fn returns false.
+func (t *Tree[K, V]) Ascend(fn func(key K, value V) bool) {
+	stack := make([]*node[K, V], 0, t.depthLimit()+1)
+	n := t.root
+	for n != nil || len(stack) > 0 {
+		for n != nil {
+			stack = append(stack, n)
+			n = n.left
+		}
+		n = stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !fn(n.key, n.value) {
+			return
+		}
+		n = n.right
+	}
+}
+
+// Keys returns all keys in ascending order.
+func (t *Tree[K, V]) Keys() []K {
+	keys := make([]K, 0, t.size)
+	t.Ascend(func(key K, _ V) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// depthLimit is the deepest an alpha-height-balanced tree of the current
+// maximum size may grow, floor(log_{1/alpha}(maxSize)). It is 0 for trees
+// of at most one key, where the logarithm would be -Inf or 0.
+func (t *Tree[K, V]) depthLimit() int {
+	if t.maxSize <= 1 {
+		return 0
+	}
+	return int(math.Log(float64(t.maxSize)) / t.logInv)
+}
+
+// rebalance walks back up the insertion path, finds the first ancestor
+// whose subtree is not alpha-weight-balanced and rebuilds it.
+func (t *Tree[K, V]) rebalance(path []*node[K, V]) {
+	childSize := 1
+	for i := len(path) - 2; i >= 0; i-- {
+		parent, child := path[i], path[i+1]
+		sibling := parent.left
+		if sibling == child {
+			sibling = parent.right
+		}
+		parentSize := childSize + count(sibling) + 1
+		if float64(childSize) > t.alpha*float64(parentSize) {
+			rebuilt := build(flatten(parent, make([]*node[K, V], 0, parentSize)))
+			t.record("rebuilds", 1)
+			t.record("rebuilt_nodes", parentSize)
+			switch {
+			case i == 0:
+				t.root = rebuilt
+			case path[i-1].left == parent:
+				path[i-1].left = rebuilt
+			default:
+				path[i-1].right = rebuilt
+			}
+			return
+		}
+		childSize = parentSize
+	}
+}
+
+// unlink removes n from its subtree and returns the subtree's new root.
+func (t *Tree[K, V]) unlink(n *node[K, V]) *node[K, V] {
+	if n.left == nil {
+		return n.right
+	}
+	if n.right == nil {
+		return n.left
+	}
+	link := &n.right
+	for (*link).left != nil {
+		link = &(*link).left
+	}
+	succ := *link
+	*link = succ.right
+	succ.left, succ.right = n.left, n.right
+	return succ
+}
+
+func count[K cmp.Ordered, V any](n *node[K, V]) int {
+	if n == nil {
+		return 0
+	}
+	return 1 + count(n.left) + count(n.right)
+}
+
+// flatten appends the nodes of the subtree rooted at n to dst in order.
+func flatten[K cmp.Ordered, V any](n *node[K, V], dst []*node[K, V]) []*node[K, V] {
+	for n != nil {
+		dst = flatten(n.left, dst)
+		dst = append(dst, n)
+		n = n.right
+	}
+	return dst
+}
+
+// build links the sorted nodes into a perfectly balanced subtree.
+func build[K cmp.Ordered, V any](nodes []*node[K, V]) *node[K, V] {
+	if len(nodes) == 0 {
+		return nil
+	}
+	mid := len(nodes) / 2
+	n := nodes[mid]
+	n.left = build(nodes[:mid])
+	n.right = build(nodes[mid+1:])
+	return n
+}
@@ -0,0 +1,199 @@
+package scapegoat
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+	"unsafe"
+
+	"github.com/anon-org/ds/dstest"
+	"github.com/anon-org/ds/metrics"
+)
+
+func TestPutGetDelete(t *testing.T) {
+	tr := New[string, int]()
+	for i, k := range []string{"m", "c", "x", "a", "e", "z"} {
+		tr.Put(k, i)
+	}
+	tr.Put("c", 100)
+	if v, ok := tr.Get("c"); !ok || v != 100 {
+		t.Fatalf("Get(c) = %v, %v; want 100, true", v, ok)
+	}
+	if tr.Contains("q") {
+		t.Fatal("Contains(q) = true")
+	}
+	if !tr.Delete("m") || tr.Delete("m") {
+		t.Fatal("Delete(m) should succeed once")
+	}
+	if got, want := tr.Keys(), []string{"a", "c", "e", "x", "z"}; !slices.Equal(got, want) {
+		t.Fatalf("Keys() = %v, want %v", got, want)
+	}
+	if k, _, _ := tr.Min(); k != "a" {
+		t.Fatalf("Min() = %q", k)
+	}
+	if k, _, _ := tr.Max(); k != "z" {
+		t.Fatalf("Max() = %q", k)
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	tr := New[int, int]()
+	if keys := tr.Keys(); len(keys) != 0 {
+		t.Fatalf("Keys() = %v on empty tree", keys)
+	}
+	if _, _, ok := tr.Min(); ok {
+		t.Fatal("Min() ok on empty tree")
+	}
+	tr.Put(1, 1)
+	tr.Delete(1)
+	if keys := tr.Keys(); len(keys) != 0 {
+		t.Fatalf("Keys() = %v after deleting the only key", keys)
+	}
+	tr.Put(2, 2)
+	if keys := tr.Keys(); !slices.Equal(keys, []int{2}) {
+		t.Fatalf("Keys() = %v, want [2]", keys)
+	}
+}
+
+func TestRandomAgainstMap(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	tr := NewWithAlpha[int, int](0.6)
+	ref := map[int]int{}
+	for i := 0; i < 20000; i++ {
+		k := r.Intn(2000)
+		switch r.Intn(3) {
+		case 0, 1:
+			tr.Put(k, i)
+			ref[k] = i
+		case 2:
+			_, want := ref[k]
+			if got := tr.Delete(k); got != want {
+				t.Fatalf("Delete(%d) = %v, want %v", k, got, want)
+			}
+			delete(ref, k)
+		}
+		want, wantOK := ref[k]
+		if v, ok := tr.Get(k); v != want || ok != wantOK {
+			t.Fatalf("Get(%d) = %v, %v; want %v, %v", k, v, ok, want, wantOK)
+		}
+		if tr.Len() != len(ref) {
+			t.Fatalf("Len() = %d, want %d", tr.Len(), len(ref))
+		}
+	}
+	want := make([]int, 0, len(ref))
+	for k := range ref {
+		want = append(want, k)
+	}
+	slices.Sort(want)
+	if got := tr.Keys(); !slices.Equal(got, want) {
+		t.Fatalf("Keys() mismatch")
+	}
+	checkDepth(t, tr)
+}
+
+func TestSequentialInsertStaysShallow(t *testing.T) {
+	tr := New[int, struct{}]()
+	for i := 0; i < 1<<14; i++ {
+		tr.Put(i, struct{}{})
+	}
+	checkDepth(t, tr)
+}
+
+func checkDepth[V any](t *testing.T, tr *Tree[int, V]) {
+	t.Helper()
+	var depth func(n *node[int, V]) int
+	depth = func(n *node[int, V]) int {
+		if n == nil {
+			return 0
+		}
+		return 1 + max(depth(n.left), depth(n.right))
+	}
+	limit := int(math.Log(float64(tr.maxSize))/tr.logInv) + 1
+	if d := depth(tr.root); d > limit {
+		t.Fatalf("depth %d exceeds %d for %d keys", d, limit, tr.Len())
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	var c metrics.Counters
+	tr := New[int, int]()
+	tr.SetRecorder(&c)
+	r := rand.New(rand.NewSource(4))
+	inserts, deletes, depth := 0, 0, 0
+	for i := 0; i < 5000; i++ {
+		k := r.Intn(500)
+		if r.Intn(3) == 0 {
+			if tr.Delete(k) {
+				deletes++
+			}
+		} else {
+			if !tr.Contains(k) {
+				inserts++
+				// The new key hangs below every node on its search path.
+				for n := tr.root; n != nil; depth++ {
+					if k < n.key {
+						n = n.left
+					} else {
+						n = n.right
+					}
+				}
+			}
+			tr.Put(k, i)
+		}
+	}
+	if got := c.Get("insert_depth"); got != int64(depth) {
+		t.Errorf("insert_depth = %d, want %d", got, depth)
+	}
+	if got := c.Get("inserts"); got != int64(inserts) {
+		t.Errorf("inserts = %d, want %d", got, inserts)
+	}
+	if got := c.Get("deletes"); got != int64(deletes) {
+		t.Errorf("deletes = %d, want %d", got, deletes)
+	}
+	if c.Get("rebuilds") == 0 || c.Get("rebuilt_nodes") < c.Get("rebuilds") {
+		t.Errorf("rebuilds = %d, rebuilt_nodes = %d", c.Get("rebuilds"), c.Get("rebuilt_nodes"))
+	}
+	tr.SetRecorder(nil)
+	tr.Put(-1, 0)
+	if got := c.Get("inserts"); got != int64(inserts) {
+		t.Errorf("insert recorded after SetRecorder(nil)")
+	}
+}
+
+func TestCheckOrdered(t *testing.T) {
+	for seed := range int64(10) {
+		cfg := dstest.Config[int, int]{
+			Ops:   5000,
+			Seed:  seed,
+			Key:   dstest.IntKeys(200),
+			Value: func(r *rand.Rand) int { return r.Int() },
+		}
+		if err := dstest.CheckOrdered[int, int](NewWithAlpha[int, int](0.55), cfg); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSizeof(t *testing.T) {
+	tr := New[int, string]()
+	empty := tr.Sizeof()
+	nodeSize := int(unsafe.Sizeof(node[int, string]{}))
+	r := rand.New(rand.NewSource(8))
+	for i := range 5000 {
+		k := r.Intn(2000)
+		if i%3 == 0 {
+			tr.Delete(k)
+		} else {
+			tr.Put(k, "v")
+		}
+		// Rebuilds relink existing nodes, so the count of reachable nodes
+		// must always match the number of keys.
+		if i%500 == 0 && count(tr.root) != tr.Len() {
+			t.Fatalf("step %d: %d reachable nodes for %d keys", i, count(tr.root), tr.Len())
+		}
+	}
+	if got, want := tr.Sizeof(), empty+tr.Len()*nodeSize; got != want {
+		t.Fatalf("Sizeof() = %d with %d keys, want %d", got, tr.Len(), want)
+	}
+}
@@ -0,0 +1,192 @@
+// Package sqrtdecomp implements square-root decomposition: a sequence split
+// into blocks of about √n elements, each caching the aggregate of its
+// elements and a pending range update.
+//
+// Range queries and range updates touch O(√n) blocks plus at most two
+// partial blocks, so both run in O(√n). The structure needs only an
+// aggregate function and, for range updates, a way to apply an update to
+// an aggregate, which makes it a simpler alternative to a lazy segment tree
+// when the extra constant factor is acceptable.
+package sqrtdecomp
+
+import (
+	"math"
+	"unsafe"
+)
+
+// Ops describes how elements are aggregated and updated. T is the element
+// (and aggregate) type and U the update type.
+type Ops[T, U any] struct {
+	// Identity is the neutral element of Combine.
+	Identity T
+	// Combine merges two aggregates. It must be associative.
+	Combine func(a, b T) T
+	// Apply returns the result of applying u to an aggregate covering
+	// count elements; count is 1 when u is applied to a single element.
+	// It may be nil if RangeUpdate is never called.
+	Apply func(agg T, u U, count int) T
+	// Compose returns the update equivalent to applying first and then
+	// second. It may be nil if RangeUpdate is never called.
+	Compose func(first, second U) U
+}
+
+type block[U any] struct {
+	lo, hi  int
+	pending U
+	lazy    bool
+}
+
+// Blocks is a sequence partitioned into fixed-size blocks.
+type Blocks[T, U any] struct {
+	ops    Ops[T, U]
+	values []T
+	agg    []T
+	blocks []block[U]
+	size   int
+}
+
+// New partitions a copy of values into blocks of blockSize elements. A
+// non-positive blockSize selects ⌈√n⌉.
+func New[T, U any](values []T, ops Ops[T, U], blockSize int) *Blocks[T, U] {
+	n := len(values)
+	if blockSize <= 0 {
+		blockSize = max(int(math.Ceil(math.Sqrt(float64(n)))), 1)
+	}
+	count := (n + blockSize - 1) / blockSize
+	b := &Blocks[T, U]{
+		ops:    ops,
+		values: append([]T(nil), values...),
+		agg:    make([]T, count),
+		blocks: make([]block[U], count),
+		size:   blockSize,
+	}
+	for k := range b.blocks {
+		b.blocks[k] = block[U]{lo: k * blockSize, hi: min((k+1)*blockSize, n)}
+		b.recompute(k)
+	}
+	return b
+}
+
+// Len returns the number of elements.
+func (b *Blocks[T, U]) Len() int {
+	return len(b.values)
+}
+
+// BlockSize returns the number of elements per block.
+func (b *Blocks[T, U]) BlockSize() int {
+	return b.size
+}
+
+// Sizeof returns the approximate heap usage of the structure in bytes: its
+// copy of the elements plus an aggregate and a pending update per block.
+func (b *Blocks[T, U]) Sizeof() int {
+	var elem T
+	return int(unsafe.Sizeof(*b)) + (cap(b.values)+cap(b.agg))*int(unsafe.Sizeof(elem)) +
+		cap(b.blocks)*int(unsafe.Sizeof(block[U]{}))
+}
+
+// Get returns element i.
+func (b *Blocks[T, U]) Get(i int) T {
+	b.checkIndex(i)
+	if blk := &b.blocks[i/b.size]; blk.lazy {
+		return b.ops.Apply(b.values[i], blk.pending, 1)
+	}
+	return b.values[i]
+}
+
+// Set replaces element i.
+func (b *Blocks[T, U]) Set(i int, value T) {
+	b.checkIndex(i)
+	k := i / b.size
+	b.push(k)
+	b.values[i] = value
+	b.recompute(k)
+}
+
+// Query aggregates elements [lo, hi). An empty range yields the identity.
+func (b *Blocks[T, U]) Query(lo, hi int) T {
+	b.checkRange(lo, hi)
+	acc := b.ops.Identity
+	for lo < hi {
+		k := lo / b.size
+		blk := &b.blocks[k]
+		if lo == blk.lo && hi >= blk.hi {
+			acc = b.ops.Combine(acc, b.agg[k])
+			lo = blk.hi
+			continue
+		}
+		end := min(hi, blk.hi)
+		b.push(k)
+		for ; lo < end; lo++ {
+			acc = b.ops.Combine(acc, b.values[lo])
+		}
+	}
+	return acc
+}
+
+// RangeUpdate applies u to every element in [lo, hi).
+func (b *Blocks[T, U]) RangeUpdate(lo, hi int, u U) {
+	b.checkRange(lo, hi)
+	for lo < hi {
+		k := lo / b.size
+		blk := &b.blocks[k]
+		if lo == blk.lo && hi >= blk.hi {
+			b.agg[k] = b.ops.Apply(b.agg[k], u, blk.hi-blk.lo)
+			if blk.lazy {
+				blk.pending = b.ops.Compose(blk.pending, u)
+			} else {
+				blk.pending, blk.lazy = u, true
+			}
+			lo = blk.hi
+			continue
+		}
+		end := min(hi, blk.hi)
+		b.push(k)
+		for ; lo < end; lo++ {
+			b.values[lo] = b.ops.Apply(b.values[lo], u, 1)
+		}
+		b.recompute(k)
+	}
+}
+
+// Values returns a copy of the current elements.
+func (b *Blocks[T, U]) Values() []T {
+	for k := range b.blocks {
+		b.push(k)
+	}
+	return append([]T(nil), b.values...)
+}
+
+// push applies block k's pending update to its elements.
+func (b *Blocks[T, U]) push(k int) {
+	blk := &b.blocks[k]
+	if !blk.lazy {
+		return
+	}
+	for i := blk.lo; i < blk.hi; i++ {
+		b.values[i] = b.ops.Apply(b.values[i], blk.pending, 1)
+	}
+	var zero U
+	blk.pending, blk.lazy = zero, false
+}
+
+func (b *Blocks[T, U]) recompute(k int) {
+	blk := &b.blocks[k]
+	acc := b.ops.Identity
+	for _, v := range b.values[blk.lo:blk.hi] {
+		acc = b.ops.Combine(acc, v)
+	}
+	b.agg[k] = acc
+}
+
+func (b *Blocks[T, U]) checkIndex(i int) {
+	if i < 0 || i >= len(b.values) {
+		panic("sqrtdecomp: index out of range")
+	}
+}
+
+func (b *Blocks[T, U]) checkRange(lo, hi int) {
+	if lo < 0 || hi > len(b.values) || lo > hi {
+		panic("sqrtdecomp: invalid range")
+	}
+}
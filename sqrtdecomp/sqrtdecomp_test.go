@@ -0,0 +1,99 @@
+package sqrtdecomp
+
+import (
+	"math/rand"
+	"testing"
+	"unsafe"
+)
+
+type sumMin struct{ sum, min int }
+
+var addOps = Ops[sumMin, int]{
+	Identity: sumMin{0, 1 << 62},
+	Combine: func(a, b sumMin) sumMin {
+		return sumMin{a.sum + b.sum, min(a.min, b.min)}
+	},
+	Apply: func(a sumMin, u, count int) sumMin {
+		return sumMin{a.sum + u*count, a.min + u}
+	},
+	Compose: func(first, second int) int { return first + second },
+}
+
+func TestRandomAgainstSlice(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, tc := range []struct{ n, blockSize int }{
+		{0, 0}, {1, 0}, {50, 0}, {50, 1}, {50, 7}, {50, 64}, {97, 10},
+	} {
+		model := make([]int, tc.n)
+		init := make([]sumMin, tc.n)
+		for i := range model {
+			model[i] = r.Intn(100)
+			init[i] = sumMin{model[i], model[i]}
+		}
+		b := New(init, addOps, tc.blockSize)
+		for step := 0; step < 2000; step++ {
+			lo := r.Intn(tc.n + 1)
+			hi := lo + r.Intn(tc.n+1-lo)
+			switch r.Intn(3) {
+			case 0:
+				u := r.Intn(21) - 10
+				b.RangeUpdate(lo, hi, u)
+				for i := lo; i < hi; i++ {
+					model[i] += u
+				}
+			case 1:
+				if lo < tc.n {
+					model[lo] = r.Intn(100)
+					b.Set(lo, sumMin{model[lo], model[lo]})
+				}
+			case 2:
+				want := addOps.Identity
+				for _, x := range model[lo:hi] {
+					want = addOps.Combine(want, sumMin{x, x})
+				}
+				if got := b.Query(lo, hi); got != want {
+					t.Fatalf("n=%d block=%d: Query(%d, %d) = %v, want %v", tc.n, tc.blockSize, lo, hi, got, want)
+				}
+				if lo < tc.n {
+					if got := b.Get(lo); got != (sumMin{model[lo], model[lo]}) {
+						t.Fatalf("n=%d block=%d: Get(%d) = %v, want %d", tc.n, tc.blockSize, lo, got, model[lo])
+					}
+				}
+			}
+		}
+		for i, v := range b.Values() {
+			if v.sum != model[i] {
+				t.Fatalf("n=%d block=%d: Values()[%d] = %d, want %d", tc.n, tc.blockSize, i, v.sum, model[i])
+			}
+		}
+	}
+}
+
+func TestDefaultBlockSize(t *testing.T) {
+	for _, tc := range []struct{ n, want int }{{0, 1}, {1, 1}, {16, 4}, {17, 5}, {100, 10}} {
+		b := New(make([]sumMin, tc.n), addOps, 0)
+		if got := b.BlockSize(); got != tc.want {
+			t.Errorf("BlockSize() for n=%d = %d, want %d", tc.n, got, tc.want)
+		}
+	}
+}
+
+func TestSizeof(t *testing.T) {
+	header := int(unsafe.Sizeof(Blocks[sumMin, int]{}))
+	elem := int(unsafe.Sizeof(sumMin{}))
+	perBlock := elem + int(unsafe.Sizeof(block[int]{}))
+	// 256 elements of 16 bytes fill their allocation exactly.
+	for _, tc := range []struct{ blockSize, blocks int }{{0, 16}, {1, 256}, {10, 26}, {300, 1}} {
+		b := New(make([]sumMin, 256), addOps, tc.blockSize)
+		want := header + 256*elem + tc.blocks*perBlock
+		if got := b.Sizeof(); got != want {
+			t.Fatalf("block size %d: Sizeof() = %d, want %d", tc.blockSize, got, want)
+		}
+		// Updates are kept per block and never allocate.
+		b.RangeUpdate(3, 200, 5)
+		b.Set(7, sumMin{1, 1})
+		if got := b.Sizeof(); got != want {
+			t.Fatalf("block size %d: Sizeof() = %d after updates, want %d", tc.blockSize, got, want)
+		}
+	}
+}
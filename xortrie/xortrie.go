@@ -0,0 +1,363 @@
+// Package xortrie implements a binary trie over uint64 keys.
+//
+// Keys are stored most-significant bit first, 64 levels deep, with a count
+// of keys below every node. That layout answers maximum/minimum XOR,
+// predecessor/successor, rank and prefix-count queries in O(64) time
+// regardless of how many keys are stored, which is handy for network masks
+// and bit-manipulation heavy analytics.
+package xortrie
+
+import (
+	"math"
+	"slices"
+	"unsafe"
+)
+
+const width = 64
+
+// maxNodes bounds the node count so that int32 child indices cannot wrap.
+// It is a variable so that tests can lower it.
+var maxNodes = math.MaxInt32
+
+type node struct {
+	child [2]int32
+	count int
+}
+
+// Trie is a set of uint64 keys. The zero value is an empty trie ready to
+// use. Nodes are linked by 32-bit indices; a trie holds at most 2³¹-1
+// nodes, enough for tens of millions of keys, and Insert panics rather
+// than exceed it.
+type Trie struct {
+	nodes []node
+}
+
+func (t *Trie) init() {
+	if t.nodes == nil {
+		// Node 0 is the root; child index 0 doubles as "no child" since the
+		// root is never anyone's child.
+		t.nodes = make([]node, 1, 64)
+	}
+}
+
+func bit(key uint64, level int) int {
+	return int(key>>(width-1-level)) & 1
+}
+
+// next returns the child of n in direction b if it holds any keys.
+func (t *Trie) next(n int32, b int) (int32, bool) {
+	c := t.nodes[n].child[b]
+	return c, c != 0 && t.nodes[c].count > 0
+}
+
+// Len returns the number of keys in the trie.
+func (t *Trie) Len() int {
+	if t.nodes == nil {
+		return 0
+	}
+	return t.nodes[0].count
+}
+
+// Reserve grows the trie's storage so that n more keys can be inserted
+// without another allocation. A key needs at most 64 new nodes, fewer when
+// it shares a prefix with a stored key, so this reserves for the worst
+// case, capped at the trie's node limit.
+func (t *Trie) Reserve(n int) {
+	if n < 0 {
+		panic("xortrie: negative reserve")
+	}
+	t.init()
+	t.nodes = slices.Grow(t.nodes, min(n, (maxNodes-len(t.nodes))/width)*width)
+}
+
+// ShrinkToFit drops the nodes left empty by Delete and releases spare
+// capacity, so that storage is again proportional to the keys held.
+func (t *Trie) ShrinkToFit() {
+	if t.nodes == nil {
+		return
+	}
+	live := 1
+	for _, n := range t.nodes[1:] {
+		if n.count > 0 {
+			live++
+		}
+	}
+	nodes := make([]node, 1, live)
+	nodes[0].count = t.nodes[0].count
+	var copyChildren func(from, to int32)
+	copyChildren = func(from, to int32) {
+		for b := 0; b < 2; b++ {
+			if c, ok := t.next(from, b); ok {
+				nodes = append(nodes, node{count: t.nodes[c].count})
+				id := int32(len(nodes) - 1)
+				nodes[to].child[b] = id
+				copyChildren(c, id)
+			}
+		}
+	}
+	copyChildren(0, 0)
+	t.nodes = nodes
+}
+
+// Sizeof returns the approximate heap usage of the trie in bytes. Nodes of
+// deleted keys are kept for reuse, and counted, until ShrinkToFit.
+func (t *Trie) Sizeof() int {
+	return int(unsafe.Sizeof(*t)) + cap(t.nodes)*int(unsafe.Sizeof(node{}))
+}
+
+// Contains reports whether key is in the trie.
+func (t *Trie) Contains(key uint64) bool {
+	if t.Len() == 0 {
+		return false
+	}
+	var n int32
+	for level := 0; level < width; level++ {
+		var ok bool
+		if n, ok = t.next(n, bit(key, level)); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Insert adds key and reports whether it was not already present.
+func (t *Trie) Insert(key uint64) bool {
+	t.init()
+	if t.Contains(key) {
+		return false
+	}
+	// Check for room before touching any counts, so that a panic leaves
+	// the trie intact.
+	var n int32
+	level := 0
+	for ; level < width; level++ {
+		c := t.nodes[n].child[bit(key, level)]
+		if c == 0 {
+			break
+		}
+		n = c
+	}
+	if len(t.nodes) > maxNodes-(width-level) {
+		panic("xortrie: too many nodes")
+	}
+	n = 0
+	t.nodes[0].count++
+	for level := 0; level < width; level++ {
+		b := bit(key, level)
+		c := t.nodes[n].child[b]
+		if c == 0 {
+			t.nodes = append(t.nodes, node{})
+			c = int32(len(t.nodes) - 1)
+			t.nodes[n].child[b] = c
+		}
+		t.nodes[c].count++
+		n = c
+	}
+	return true
+}
+
+// Delete removes key and reports whether it was present. Emptied nodes are
+// kept and reused by later insertions.
+func (t *Trie) Delete(key uint64) bool {
+	if !t.Contains(key) {
+		return false
+	}
+	var n int32
+	t.nodes[0].count--
+	for level := 0; level < width; level++ {
+		n = t.nodes[n].child[bit(key, level)]
+		t.nodes[n].count--
+	}
+	return true
+}
+
+// Min returns the smallest key. The boolean is false if the trie is empty.
+func (t *Trie) Min() (uint64, bool) {
+	return t.MinXor(0)
+}
+
+// Max returns the largest key. The boolean is false if the trie is empty.
+func (t *Trie) Max() (uint64, bool) {
+	return t.MinXor(^uint64(0))
+}
+
+// MaxXor returns the stored key k maximizing x^k. The boolean is false if
+// the trie is empty.
+func (t *Trie) MaxXor(x uint64) (uint64, bool) {
+	return t.MinXor(^x)
+}
+
+// MinXor returns the stored key k minimizing x^k. The boolean is false if
+// the trie is empty.
+func (t *Trie) MinXor(x uint64) (uint64, bool) {
+	if t.Len() == 0 {
+		return 0, false
+	}
+	var n int32
+	var key uint64
+	for level := 0; level < width; level++ {
+		b := bit(x, level)
+		c, ok := t.next(n, b)
+		if !ok {
+			b ^= 1
+			c, _ = t.next(n, b)
+		}
+		key = key<<1 | uint64(b)
+		n = c
+	}
+	return key, true
+}
+
+// MaxXorPair returns two stored keys whose XOR is maximal. The boolean is
+// false if the trie holds fewer than two keys.
+func (t *Trie) MaxXorPair() (a, b uint64, ok bool) {
+	if t.Len() < 2 {
+		return 0, 0, false
+	}
+	best := uint64(0)
+	t.Ascend(func(k uint64) bool {
+		m, _ := t.MaxXor(k)
+		if x := k ^ m; x > best || !ok {
+			a, b, best, ok = k, m, x, true
+		}
+		return true
+	})
+	return a, b, ok
+}
+
+// Floor returns the largest key less than or equal to x. The boolean is
+// false if there is none.
+func (t *Trie) Floor(x uint64) (uint64, bool) {
+	return t.bound(x, 0)
+}
+
+// Ceiling returns the smallest key greater than or equal to x. The boolean
+// is false if there is none.
+func (t *Trie) Ceiling(x uint64) (uint64, bool) {
+	return t.bound(x, 1)
+}
+
+// Predecessor returns the largest key strictly less than x.
+func (t *Trie) Predecessor(x uint64) (uint64, bool) {
+	if x == 0 {
+		return 0, false
+	}
+	return t.Floor(x - 1)
+}
+
+// Successor returns the smallest key strictly greater than x.
+func (t *Trie) Successor(x uint64) (uint64, bool) {
+	if x == ^uint64(0) {
+		return 0, false
+	}
+	return t.Ceiling(x + 1)
+}
+
+// bound follows x down the trie, remembering the deepest point where it
+// could have branched towards side (0 for smaller keys, 1 for larger), and
+// finishes with the extreme key of that branch if x itself is absent.
+func (t *Trie) bound(x uint64, side int) (uint64, bool) {
+	if t.Len() == 0 {
+		return 0, false
+	}
+	var n int32
+	branch, branchLevel := int32(-1), -1
+	for level := 0; level < width; level++ {
+		b := bit(x, level)
+		if b != side {
+			if c, ok := t.next(n, side); ok {
+				branch, branchLevel = c, level
+			}
+		}
+		c, ok := t.next(n, b)
+		if !ok {
+			break
+		}
+		n = c
+		if level == width-1 {
+			return x, true
+		}
+	}
+	if branch < 0 {
+		return 0, false
+	}
+	// Keep the prefix above branchLevel, take side at branchLevel, then
+	// descend towards the opposite extreme.
+	key := x>>(width-branchLevel)<<1 | uint64(side)
+	n = branch
+	for level := branchLevel + 1; level < width; level++ {
+		b := 1 - side
+		c, ok := t.next(n, b)
+		if !ok {
+			b ^= 1
+			c, _ = t.next(n, b)
+		}
+		key = key<<1 | uint64(b)
+		n = c
+	}
+	return key, true
+}
+
+// CountLess returns the number of keys strictly less than x.
+func (t *Trie) CountLess(x uint64) int {
+	if t.Len() == 0 {
+		return 0
+	}
+	var n int32
+	total := 0
+	for level := 0; level < width; level++ {
+		b := bit(x, level)
+		if b == 1 {
+			if c, ok := t.next(n, 0); ok {
+				total += t.nodes[c].count
+			}
+		}
+		c, ok := t.next(n, b)
+		if !ok {
+			break
+		}
+		n = c
+	}
+	return total
+}
+
+// CountPrefix returns the number of keys whose leading bits bits match
+// those of prefix. bits must be in [0, 64].
+func (t *Trie) CountPrefix(prefix uint64, bits int) int {
+	if bits < 0 || bits > width {
+		panic("xortrie: prefix length out of range")
+	}
+	if t.Len() == 0 {
+		return 0
+	}
+	var n int32
+	for level := 0; level < bits; level++ {
+		var ok bool
+		if n, ok = t.next(n, bit(prefix, level)); !ok {
+			return 0
+		}
+	}
+	return t.nodes[n].count
+}
+
+// Ascend calls fn for every key in ascending order until fn returns false.
+func (t *Trie) Ascend(fn func(key uint64) bool) {
+	if t.Len() == 0 {
+		return
+	}
+	t.walk(0, 0, 0, fn)
+}
+
+func (t *Trie) walk(n int32, level int, prefix uint64, fn func(uint64) bool) bool {
+	if level == width {
+		return fn(prefix)
+	}
+	for b := 0; b < 2; b++ {
+		if c, ok := t.next(n, b); ok {
+			if !t.walk(c, level+1, prefix<<1|uint64(b), fn) {
+				return false
+			}
+		}
+	}
+	return true
+}
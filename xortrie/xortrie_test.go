@@ -0,0 +1,292 @@
+package xortrie
+
+import (
+	"math"
+	"math/bits"
+	"math/rand"
+	"slices"
+	"testing"
+	"unsafe"
+)
+
+func TestRandomAgainstSortedSlice(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	pool := []uint64{0, 1, ^uint64(0), 1 << 63}
+	for len(pool) < 200 {
+		// Mix dense small keys with keys spread over all 64 bits.
+		if r.Intn(2) == 0 {
+			pool = append(pool, uint64(r.Intn(64)))
+		} else {
+			pool = append(pool, r.Uint64())
+		}
+	}
+	var tr Trie
+	var model []uint64
+	for step := 0; step < 5000; step++ {
+		k := pool[r.Intn(len(pool))]
+		i, found := slices.BinarySearch(model, k)
+		switch r.Intn(3) {
+		case 0:
+			if tr.Insert(k) == found {
+				t.Fatalf("Insert(%d) = %v, want %v", k, found, !found)
+			}
+			if !found {
+				model = slices.Insert(model, i, k)
+			}
+		case 1:
+			if tr.Delete(k) != found {
+				t.Fatalf("Delete(%d) = %v, want %v", k, !found, found)
+			}
+			if found {
+				model = slices.Delete(model, i, i+1)
+			}
+		case 2:
+			checkQueries(t, &tr, model, k)
+		}
+	}
+}
+
+func checkQueries(t *testing.T, tr *Trie, model []uint64, x uint64) {
+	t.Helper()
+	if tr.Len() != len(model) {
+		t.Fatalf("Len() = %d, want %d", tr.Len(), len(model))
+	}
+	i, found := slices.BinarySearch(model, x)
+	if tr.Contains(x) != found {
+		t.Fatalf("Contains(%d) = %v, want %v", x, !found, found)
+	}
+	if got := tr.CountLess(x); got != i {
+		t.Fatalf("CountLess(%d) = %d, want %d", x, got, i)
+	}
+
+	var floor, ceil uint64
+	hasFloor, hasCeil := false, i < len(model)
+	if found {
+		floor, hasFloor = x, true
+	} else if i > 0 {
+		floor, hasFloor = model[i-1], true
+	}
+	if hasCeil {
+		ceil = model[i]
+	}
+	if got, ok := tr.Floor(x); ok != hasFloor || got != floor {
+		t.Fatalf("Floor(%d) = %d, %v; want %d, %v", x, got, ok, floor, hasFloor)
+	}
+	if got, ok := tr.Ceiling(x); ok != hasCeil || got != ceil {
+		t.Fatalf("Ceiling(%d) = %d, %v; want %d, %v", x, got, ok, ceil, hasCeil)
+	}
+	wantPred, hasPred := uint64(0), i > 0
+	if hasPred {
+		wantPred = model[i-1]
+	}
+	if got, ok := tr.Predecessor(x); ok != hasPred || got != wantPred {
+		t.Fatalf("Predecessor(%d) = %d, %v; want %d, %v", x, got, ok, wantPred, hasPred)
+	}
+	j := i
+	if found {
+		j++
+	}
+	wantSucc, hasSucc := uint64(0), j < len(model)
+	if hasSucc {
+		wantSucc = model[j]
+	}
+	if got, ok := tr.Successor(x); ok != hasSucc || got != wantSucc {
+		t.Fatalf("Successor(%d) = %d, %v; want %d, %v", x, got, ok, wantSucc, hasSucc)
+	}
+
+	if len(model) > 0 {
+		lo, hi := model[0], model[0]
+		for _, k := range model {
+			if k^x < lo^x {
+				lo = k
+			}
+			if k^x > hi^x {
+				hi = k
+			}
+		}
+		if got, _ := tr.MinXor(x); got != lo {
+			t.Fatalf("MinXor(%d) = %d, want %d", x, got, lo)
+		}
+		if got, _ := tr.MaxXor(x); got != hi {
+			t.Fatalf("MaxXor(%d) = %d, want %d", x, got, hi)
+		}
+		if got, _ := tr.Min(); got != model[0] {
+			t.Fatalf("Min() = %d, want %d", got, model[0])
+		}
+		if got, _ := tr.Max(); got != model[len(model)-1] {
+			t.Fatalf("Max() = %d, want %d", got, model[len(model)-1])
+		}
+	}
+
+	for _, n := range []int{0, 1, 8, 60, 64} {
+		want := 0
+		for _, k := range model {
+			if n == 0 || bits.LeadingZeros64(k^x) >= n {
+				want++
+			}
+		}
+		if got := tr.CountPrefix(x, n); got != want {
+			t.Fatalf("CountPrefix(%d, %d) = %d, want %d", x, n, got, want)
+		}
+	}
+
+	var keys []uint64
+	tr.Ascend(func(k uint64) bool {
+		keys = append(keys, k)
+		return true
+	})
+	if !slices.Equal(keys, model) {
+		t.Fatalf("Ascend = %v, want %v", keys, model)
+	}
+}
+
+func TestMaxXorPair(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	var tr Trie
+	if _, _, ok := tr.MaxXorPair(); ok {
+		t.Fatal("MaxXorPair on empty trie reported a pair")
+	}
+	var keys []uint64
+	for i := 0; i < 100; i++ {
+		k := r.Uint64() >> uint(r.Intn(64))
+		tr.Insert(k)
+		keys = append(keys, k)
+	}
+	best := uint64(0)
+	for _, a := range keys {
+		for _, b := range keys {
+			best = max(best, a^b)
+		}
+	}
+	a, b, ok := tr.MaxXorPair()
+	if !ok || a^b != best || !tr.Contains(a) || !tr.Contains(b) {
+		t.Fatalf("MaxXorPair() = %d, %d, %v; want xor %d", a, b, ok, best)
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	var tr Trie
+	if tr.Len() != 0 || tr.Contains(0) || tr.Delete(0) {
+		t.Fatal("zero Trie is not empty")
+	}
+	if _, ok := tr.Min(); ok {
+		t.Fatal("Min on empty trie reported a key")
+	}
+	if got := tr.CountPrefix(0, 0); got != 0 {
+		t.Fatalf("CountPrefix on empty trie = %d", got)
+	}
+}
+
+func TestReserveAndShrinkToFit(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	var tr Trie
+	tr.Reserve(100)
+	before := cap(tr.nodes)
+	keys := make([]uint64, 100)
+	for i := range keys {
+		keys[i] = r.Uint64()
+		tr.Insert(keys[i])
+	}
+	if cap(tr.nodes) != before {
+		t.Fatalf("inserting reserved keys reallocated")
+	}
+
+	var fresh Trie
+	for _, k := range keys[:10] {
+		fresh.Insert(k)
+	}
+	for _, k := range keys[10:] {
+		tr.Delete(k)
+	}
+	tr.ShrinkToFit()
+	if len(tr.nodes) != len(fresh.nodes) || cap(tr.nodes) != len(tr.nodes) {
+		t.Fatalf("ShrinkToFit kept %d nodes (cap %d), want %d", len(tr.nodes), cap(tr.nodes), len(fresh.nodes))
+	}
+	model := slices.Sorted(slices.Values(keys[:10]))
+	for _, x := range append(slices.Clone(keys), 0, ^uint64(0)) {
+		checkQueries(t, &tr, model, x)
+	}
+	// The compacted trie keeps accepting keys.
+	tr.Insert(keys[50])
+	if !tr.Contains(keys[50]) || tr.Len() != 11 {
+		t.Fatal("Insert after ShrinkToFit failed")
+	}
+
+	var empty Trie
+	empty.ShrinkToFit()
+	if empty.Len() != 0 {
+		t.Fatal("ShrinkToFit on zero Trie changed it")
+	}
+}
+
+func TestTooManyNodes(t *testing.T) {
+	defer func(n int) { maxNodes = n }(maxNodes)
+	// The root, one full path of 64 nodes and the 4 that keys 1, 2 and 3
+	// add below it.
+	maxNodes = 1 + width + 4
+	var tr Trie
+	tr.Insert(0)
+	tr.Insert(1) // shares 63 nodes with 0
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("Insert past maxNodes did not panic")
+			}
+		}()
+		tr.Insert(1 << 63)
+	}()
+	if tr.Len() != 2 || !tr.Contains(0) || !tr.Contains(1) || tr.Contains(1<<63) {
+		t.Fatalf("after the failed Insert: Len %d", tr.Len())
+	}
+	if !tr.Insert(2) || !tr.Insert(3) {
+		t.Fatal("Insert within maxNodes failed")
+	}
+	var got []uint64
+	tr.Ascend(func(key uint64) bool {
+		got = append(got, key)
+		return true
+	})
+	if !slices.Equal(got, []uint64{0, 1, 2, 3}) {
+		t.Fatalf("keys = %v, want [0 1 2 3]", got)
+	}
+
+	// Reserve caps its worst-case estimate instead of overflowing.
+	var big Trie
+	big.Reserve(math.MaxInt)
+	if cap(big.nodes) > 2*maxNodes {
+		t.Fatalf("Reserve(MaxInt) allocated %d nodes", cap(big.nodes))
+	}
+}
+
+func TestSizeof(t *testing.T) {
+	var tr Trie
+	header := int(unsafe.Sizeof(tr))
+	if got := tr.Sizeof(); got != header {
+		t.Fatalf("Sizeof() of the zero Trie = %d, want %d", got, header)
+	}
+	r := rand.New(rand.NewSource(4))
+	keys := map[uint64]bool{}
+	for range 2000 {
+		// Few high bits keep many shared prefixes.
+		k := r.Uint64() >> r.Intn(60)
+		tr.Insert(k)
+		keys[k] = true
+		if r.Intn(3) == 0 {
+			tr.Delete(k)
+			delete(keys, k)
+		}
+	}
+	tr.ShrinkToFit()
+	// Once compacted the trie holds the root plus one node per distinct
+	// non-empty prefix of the stored keys.
+	prefixes := map[[2]uint64]bool{}
+	for k := range keys {
+		for l := 1; l <= width; l++ {
+			prefixes[[2]uint64{uint64(l), k >> (width - l)}] = true
+		}
+	}
+	want := header + (1+len(prefixes))*int(unsafe.Sizeof(node{}))
+	if got := tr.Sizeof(); got != want {
+		t.Fatalf("Sizeof() = %d for %d keys, want %d", got, len(keys), want)
+	}
+}